@@ -336,6 +336,29 @@ func (c *API) SendMessage(
 	return c.Send(ctx, cfg)
 }
 
+// SendLongMessage sends text message that can be longer than
+// MaxMessageLength. Text is split by SplitMessage and sent
+// as several messages. It returns sent messages, they are
+// returned with error too if not all messages were sent.
+func (c *API) SendLongMessage(
+	ctx context.Context,
+	cfg MessageCfg) ([]*Message, error) {
+
+	parts, err := SplitMessage(cfg, MaxMessageLength)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*Message, 0, len(parts))
+	for _, part := range parts {
+		msg, err := c.SendMessage(ctx, part)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
 // SendSticker sends message with sticker.
 func (c *API) SendSticker(
 	ctx context.Context,
@@ -462,6 +485,24 @@ func (c *API) SetWebhook(ctx context.Context, cfg WebhookCfg) error {
 	return c.Invoke(ctx, cfg, nil)
 }
 
+// DeleteWebhook removes webhook integration
+// if you decide to switch back to GetUpdates.
+// Pass DropPendingUpdates to drop all pending updates.
+func (c *API) DeleteWebhook(ctx context.Context, cfg DeleteWebhookCfg) error {
+	return c.Invoke(ctx, cfg, nil)
+}
+
+// GetWebhookInfo returns current webhook status.
+// If the bot is using GetUpdates, will return an object
+// with the URL field empty.
+func (c *API) GetWebhookInfo(ctx context.Context) (*WebhookInfo, error) {
+	info := &WebhookInfo{}
+	if err := c.Invoke(ctx, WebhookInfoCfg{}, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
 // AnswerInlineQuery sends answers to an inline query.
 // On success, True is returned. No more than 50 results per query are allowed.
 func (c *API) AnswerInlineQuery(ctx context.Context, cfg AnswerInlineQueryCfg) (bool, error) {
@@ -620,6 +661,7 @@ func (c *API) makeRequest(
 		return &APIError{
 			Description: apiResponse.Description,
 			ErrorCode:   apiResponse.ErrorCode,
+			Parameters:  apiResponse.Parameters,
 		}
 	}
 	if dst != nil && apiResponse.Result != nil {
@@ -324,3 +324,89 @@ func TestAPI_DownloadFile(t *testing.T) {
 	assert.Equal(t, "FILE DATA", buf.String())
 
 }
+
+func TestAPI_GetWebhookInfo(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	testTable := []struct {
+		resp      httpmock.Responder
+		expErr    string
+		expResult *telegram.WebhookInfo
+	}{
+		{
+			resp: httpmock.NewStringResponder(200, `
+			{
+			    "ok": true,
+			    "result":
+			    {
+			    	"url": "https://example.com/hook",
+			    	"has_custom_certificate": false,
+			    	"pending_update_count": 3,
+			    	"last_error_date": 1500000000,
+			    	"last_error_message": "Connection refused",
+			    	"max_connections": 40,
+			    	"allowed_updates": ["message"]
+			    }
+			}`),
+			expResult: &telegram.WebhookInfo{
+				URL:                "https://example.com/hook",
+				PendingUpdateCount: 3,
+				LastErrorDate:      1500000000,
+				LastErrorMessage:   "Connection refused",
+				MaxConnections:     40,
+				AllowedUpdates:     []string{"message"},
+			},
+		},
+		{
+			resp:   forbiddenResponder,
+			expErr: "forbidden",
+		},
+	}
+	for i, tt := range testTable {
+		t.Logf("Experiment %d", i)
+
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.telegram.org/bottoken/getWebhookInfo",
+			tt.resp,
+		)
+
+		result, err := api.GetWebhookInfo(ctx)
+		if tt.expErr != "" {
+			assert.EqualError(t, err, tt.expErr)
+		} else {
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, tt.expResult, result)
+
+		httpmock.Reset()
+	}
+}
+
+func TestAPI_DeleteWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	var dropPending string
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/deleteWebhook",
+		func(req *http.Request) (*http.Response, error) {
+			dropPending = req.FormValue("drop_pending_updates")
+			return httpmock.NewStringResponse(200,
+				`{"ok": true, "result": true}`), nil
+		},
+	)
+	err := api.DeleteWebhook(ctx, telegram.DeleteWebhookCfg{
+		DropPendingUpdates: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "true", dropPending)
+}
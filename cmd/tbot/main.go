@@ -37,6 +37,7 @@ var completer = readline.NewPrefixCompleter(
 	readline.PcItem("openChat"),
 	readline.PcItem("setWebhook"),
 	readline.PcItem("removeWebhook"),
+	readline.PcItem("getWebhookInfo"),
 )
 
 func readToken(rl *readline.Instance) (string, error) {
@@ -88,6 +89,15 @@ func getMe(rl *readline.Instance, ctx context.Context, cl *telegram.API) {
 
 }
 
+func getWebhookInfo(rl *readline.Instance, ctx context.Context, cl *telegram.API) {
+	info, err := cl.GetWebhookInfo(ctx)
+	if err != nil {
+		log.Printf("getWebhookInfo error: %s\n", err.Error())
+		return
+	}
+	writeJSON(rl, "getWebhookInfo", info)
+}
+
 func openChat(
 	ctx context.Context,
 	rl *readline.Instance,
@@ -101,47 +111,21 @@ func openChat(
 	if err != nil {
 		return err
 	}
-	updateCh := make(chan *telegram.Update)
+	updateCh := make(chan telegram.Update)
 	upCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	go func(
-		ctx context.Context,
-		cfg telegram.UpdateCfg,
-		out chan<- *telegram.Update) {
-	loop:
-		for {
-			updates, err := cl.GetUpdates(ctx, cfg)
-			if err != nil {
-				if err == context.Canceled {
-					close(out)
-					return
-				}
-				log.Println(err)
-				if telegram.IsForbiddenError(err) {
-					close(out)
-					return
-				}
-				log.Println("Failed to get updates, retrying in 3 seconds...")
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(time.Second * 3):
-					continue loop
-				}
-			}
-
-			for _, update := range updates {
-				if update.UpdateID >= cfg.Offset {
-					cfg.Offset = update.UpdateID + 1
-					select {
-					case <-ctx.Done():
-						return
-					case out <- &update:
-					}
-				}
-			}
+	retry := telegram.DefaultRetryConfig
+	retry.OnRetry = func(err error, attempt int, delay time.Duration) {
+		log.Printf("Failed to get updates: %s, retrying in %s...\n",
+			err.Error(), delay)
+	}
+	go func() {
+		err := telegram.GetUpdatesWithRetry(
+			upCtx, cl, telegram.UpdateCfg{Timeout: 30}, retry, updateCh)
+		if err != nil && err != context.Canceled {
+			log.Println(err)
 		}
-	}(upCtx, telegram.UpdateCfg{Timeout: 30}, updateCh)
+	}()
 
 	go func() {
 		for {
@@ -255,6 +239,8 @@ loop:
 			help(rl, line[5:])
 		case line == "getMe":
 			getMe(rl, ctx, cl)
+		case line == "getWebhookInfo":
+			getWebhookInfo(rl, ctx, cl)
 		case line == "getUpdates":
 			getUpdates(rl, ctx, cl, telegram.UpdateCfg{})
 		case strings.HasPrefix(line, "getUpdates"):
@@ -403,7 +389,7 @@ loop:
 				continue loop
 			}
 		case line == "removeWebhook":
-			err := cl.SetWebhook(ctx, telegram.NewWebhook(""))
+			err := cl.DeleteWebhook(ctx, telegram.DeleteWebhookCfg{})
 			if err != nil {
 				log.Println(err.Error())
 				continue loop
@@ -334,6 +334,25 @@ type WebhookCfg struct {
 	URL string
 	// self generated TLS certificate
 	Certificate InputFile
+	// A secret token to be sent in a header
+	// “X-Telegram-Bot-Api-Secret-Token” in every webhook request,
+	// 1-256 characters. Only characters A-Z, a-z, 0-9, _ and -
+	// are allowed. Optional.
+	SecretToken string
+	// Maximum allowed number of simultaneous HTTPS connections
+	// to the webhook for update delivery, 1-100. Defaults to 40.
+	// Optional.
+	MaxConnections int
+	// List the types of updates you want your bot to receive,
+	// e.x. []string{"message", "callback_query"}.
+	// Nil value means that previous setting is used,
+	// empty (non nil) slice means all update types. Optional.
+	AllowedUpdates []string
+	// The fixed IP address which will be used to send webhook requests
+	// instead of the IP address resolved through DNS. Optional.
+	IPAddress string
+	// Pass True to drop all pending updates. Optional.
+	DropPendingUpdates bool
 }
 
 // Name method returns Telegram API method name for sending Location.
@@ -342,9 +361,41 @@ func (cfg WebhookCfg) Name() string {
 }
 
 // Values returns a url.Values representation of Webhook config.
+// Returns ValidationError if SecretToken has wrong format.
 func (cfg WebhookCfg) Values() (url.Values, error) {
 	v := url.Values{}
 	v.Add("url", cfg.URL)
+	if cfg.SecretToken != "" {
+		if !secretTokenRegex.MatchString(cfg.SecretToken) {
+			return nil, NewValidationError(
+				"SecretToken",
+				"should be 1-256 characters of A-Z, a-z, 0-9, _ and -",
+			)
+		}
+		v.Add("secret_token", cfg.SecretToken)
+	}
+	if cfg.MaxConnections != 0 {
+		if cfg.MaxConnections < 1 || cfg.MaxConnections > 100 {
+			return nil, NewValidationError(
+				"MaxConnections",
+				"should be between 1 and 100",
+			)
+		}
+		v.Add("max_connections", strconv.Itoa(cfg.MaxConnections))
+	}
+	if cfg.AllowedUpdates != nil {
+		data, err := json.Marshal(cfg.AllowedUpdates)
+		if err != nil {
+			return nil, err
+		}
+		v.Add("allowed_updates", string(data))
+	}
+	if cfg.IPAddress != "" {
+		v.Add("ip_address", cfg.IPAddress)
+	}
+	if cfg.DropPendingUpdates {
+		v.Add("drop_pending_updates", strconv.FormatBool(cfg.DropPendingUpdates))
+	}
 	return v, nil
 }
 
@@ -374,6 +425,39 @@ func (cfg *WebhookCfg) GetFileID() string {
 	return ""
 }
 
+// DeleteWebhookCfg contains information about a deleteWebhook request.
+type DeleteWebhookCfg struct {
+	// Pass True to drop all pending updates. Optional.
+	DropPendingUpdates bool
+}
+
+// Name returns method name
+func (cfg DeleteWebhookCfg) Name() string {
+	return deleteWebhookMethod
+}
+
+// Values returns a url.Values representation of DeleteWebhookCfg.
+func (cfg DeleteWebhookCfg) Values() (url.Values, error) {
+	v := url.Values{}
+	if cfg.DropPendingUpdates {
+		v.Add("drop_pending_updates", strconv.FormatBool(cfg.DropPendingUpdates))
+	}
+	return v, nil
+}
+
+// WebhookInfoCfg contains information about a getWebhookInfo request.
+type WebhookInfoCfg struct{}
+
+// Name returns method name
+func (cfg WebhookInfoCfg) Name() string {
+	return getWebhookInfoMethod
+}
+
+// Values for getWebhookInfo is empty
+func (cfg WebhookInfoCfg) Values() (url.Values, error) {
+	return nil, nil
+}
+
 // AnswerCallbackCfg contains information on making a anserCallbackQuery response.
 type AnswerCallbackCfg struct {
 	CallbackQueryID string `json:"callback_query_id"`
@@ -431,10 +515,11 @@ func (cfg AnswerInlineQueryCfg) Name() string {
 }
 
 // Values returns a url.Values representation of AnswerInlineQueryCfg.
-// Returns a RequiredError if Action is empty.
+// Returns a RequiredError if Results is nil,
+// use an empty slice to answer that there are no results.
 func (cfg AnswerInlineQueryCfg) Values() (url.Values, error) {
 	v := url.Values{}
-	if cfg.Results == nil || len(cfg.Results) == 0 {
+	if cfg.Results == nil {
 		return nil, NewRequiredError("Results")
 	}
 	data, err := json.Marshal(cfg.Results)
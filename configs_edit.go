@@ -59,6 +59,9 @@ type EditMessageTextCfg struct {
 	// to show bold, italic, fixed-width text
 	// or inline URLs in your bot's message. Optional.
 	ParseMode string
+	// Special entities that appear in message text,
+	// it can be used instead of ParseMode. Optional.
+	Entities []MessageEntity
 	// Disables link previews for links in this message. Optional.
 	DisableWebPagePreview bool
 }
@@ -74,6 +77,13 @@ func (cfg EditMessageTextCfg) Values() (url.Values, error) {
 	if cfg.ParseMode != "" {
 		v.Add("parse_mode", cfg.ParseMode)
 	}
+	if len(cfg.Entities) > 0 {
+		data, err := json.Marshal(cfg.Entities)
+		if err != nil {
+			return nil, err
+		}
+		v.Add("entities", string(data))
+	}
 	if cfg.DisableWebPagePreview {
 		v.Add("disable_web_page_preview", "true")
 	}
@@ -80,6 +80,9 @@ type MessageCfg struct {
 	// bold, italic, fixed-width text or inline URLs in your bot's message.
 	// Use one of constants: ModeHTML, ModeMarkdown.
 	ParseMode string
+	// Special entities that appear in message text,
+	// it can be used instead of ParseMode. Optional.
+	Entities []MessageEntity
 	// Disables link previews for links in this message.
 	DisableWebPagePreview bool
 }
@@ -109,6 +112,9 @@ func (cfg MessageCfg) Values() (url.Values, error) {
 	if cfg.ParseMode != "" {
 		v.Add("parse_mode", cfg.ParseMode)
 	}
+	if err = addEntities(v, "entities", cfg.Entities); err != nil {
+		return nil, err
+	}
 
 	return v, nil
 }
@@ -325,7 +331,11 @@ func (b *BaseFile) Reset(i InputFile) {
 // Implements Filer and Messenger interfaces.
 type PhotoCfg struct {
 	BaseFile
+	// Caption of the file, 0-200 characters. Optional.
 	Caption string
+	// Special entities that appear in caption,
+	// it can be used instead of parse mode. Optional.
+	CaptionEntities []MessageEntity
 }
 
 // Name returns method name
@@ -345,6 +355,9 @@ func (cfg PhotoCfg) Values() (url.Values, error) {
 	if cfg.Caption != "" {
 		v.Add("caption", cfg.Caption)
 	}
+	if err = addEntities(v, "caption_entities", cfg.CaptionEntities); err != nil {
+		return nil, err
+	}
 	return v, nil
 }
 
@@ -361,6 +374,11 @@ type AudioCfg struct {
 	Duration  int
 	Performer string
 	Title     string
+	// Caption of the file, 0-200 characters. Optional.
+	Caption string
+	// Special entities that appear in caption,
+	// it can be used instead of parse mode. Optional.
+	CaptionEntities []MessageEntity
 }
 
 // Name returns method name
@@ -387,6 +405,12 @@ func (cfg AudioCfg) Values() (url.Values, error) {
 	if cfg.Title != "" {
 		v.Add("title", cfg.Title)
 	}
+	if cfg.Caption != "" {
+		v.Add("caption", cfg.Caption)
+	}
+	if err = addEntities(v, "caption_entities", cfg.CaptionEntities); err != nil {
+		return nil, err
+	}
 	return v, nil
 }
 
@@ -401,7 +425,11 @@ func (cfg AudioCfg) Field() string {
 type VideoCfg struct {
 	BaseFile
 	Duration int
-	Caption  string
+	// Caption of the file, 0-200 characters. Optional.
+	Caption string
+	// Special entities that appear in caption,
+	// it can be used instead of parse mode. Optional.
+	CaptionEntities []MessageEntity
 }
 
 // Name returns method name
@@ -425,6 +453,9 @@ func (cfg VideoCfg) Values() (url.Values, error) {
 	if cfg.Caption != "" {
 		v.Add("caption", cfg.Caption)
 	}
+	if err = addEntities(v, "caption_entities", cfg.CaptionEntities); err != nil {
+		return nil, err
+	}
 	return v, nil
 }
 
@@ -439,6 +470,11 @@ func (cfg VideoCfg) Field() string {
 type VoiceCfg struct {
 	BaseFile
 	Duration int
+	// Caption of the file, 0-200 characters. Optional.
+	Caption string
+	// Special entities that appear in caption,
+	// it can be used instead of parse mode. Optional.
+	CaptionEntities []MessageEntity
 }
 
 // Name returns method name
@@ -461,6 +497,12 @@ func (cfg VoiceCfg) Values() (url.Values, error) {
 	if cfg.Duration != 0 {
 		v.Add("duration", strconv.Itoa(cfg.Duration))
 	}
+	if cfg.Caption != "" {
+		v.Add("caption", cfg.Caption)
+	}
+	if err = addEntities(v, "caption_entities", cfg.CaptionEntities); err != nil {
+		return nil, err
+	}
 
 	return v, nil
 }
@@ -475,6 +517,11 @@ func (cfg VoiceCfg) Field() string {
 // Implements Filer and Messenger interfaces.
 type DocumentCfg struct {
 	BaseFile
+	// Caption of the file, 0-200 characters. Optional.
+	Caption string
+	// Special entities that appear in caption,
+	// it can be used instead of parse mode. Optional.
+	CaptionEntities []MessageEntity
 }
 
 // Name returns method name
@@ -491,6 +538,12 @@ func (cfg DocumentCfg) Values() (url.Values, error) {
 	if cfg.BaseFile.FileID != "" {
 		v.Add(cfg.Field(), cfg.BaseFile.FileID)
 	}
+	if cfg.Caption != "" {
+		v.Add("caption", cfg.Caption)
+	}
+	if err = addEntities(v, "caption_entities", cfg.CaptionEntities); err != nil {
+		return nil, err
+	}
 
 	return v, nil
 }
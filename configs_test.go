@@ -222,13 +222,14 @@ func TestAnswerInlineQueryCfg_Values(t *testing.T) {
 			),
 		},
 		{
+			exp: url.Values{
+				"results":         {"[]"},
+				"inline_query_id": {"10"},
+			},
 			cfg: telegram.AnswerInlineQueryCfg{
 				InlineQueryID: "10",
 				Results:       []telegram.InlineQueryResult{},
 			},
-			expErr: telegram.NewRequiredError(
-				"Results",
-			),
 		},
 		{
 			cfg: telegram.AnswerInlineQueryCfg{
@@ -521,3 +522,107 @@ func TestLeaveChatCfg_Values(t *testing.T) {
 		assert.Equal(t, tt.exp, values)
 	}
 }
+
+func TestWebhookCfg_Name(t *testing.T) {
+	name := "setWebhook"
+	c := telegram.WebhookCfg{}
+	if c.Name() != name {
+		t.Errorf("Expected Name() to be %s, actual %s", name, c.Name())
+	}
+}
+
+func TestWebhookCfg_Values(t *testing.T) {
+	testTable := []cfgTT{
+		{
+			exp: url.Values{
+				"url": {"https://example.com/hook"},
+			},
+			cfg: telegram.WebhookCfg{
+				URL: "https://example.com/hook",
+			},
+		},
+		{
+			exp: url.Values{
+				"url":          {"https://example.com/hook"},
+				"secret_token": {"secret_Token-1"},
+			},
+			cfg: telegram.WebhookCfg{
+				URL:         "https://example.com/hook",
+				SecretToken: "secret_Token-1",
+			},
+		},
+		{
+			exp: url.Values{
+				"url":                  {"https://example.com/hook"},
+				"max_connections":      {"10"},
+				"allowed_updates":      {`["message","callback_query"]`},
+				"ip_address":           {"1.2.3.4"},
+				"drop_pending_updates": {"true"},
+			},
+			cfg: telegram.WebhookCfg{
+				URL:                "https://example.com/hook",
+				MaxConnections:     10,
+				AllowedUpdates:     []string{"message", "callback_query"},
+				IPAddress:          "1.2.3.4",
+				DropPendingUpdates: true,
+			},
+		},
+		{
+			exp: url.Values{
+				"url":             {"https://example.com/hook"},
+				"allowed_updates": {`[]`},
+			},
+			cfg: telegram.WebhookCfg{
+				URL:            "https://example.com/hook",
+				AllowedUpdates: []string{},
+			},
+		},
+		{
+			cfg: telegram.WebhookCfg{
+				URL:            "https://example.com/hook",
+				MaxConnections: 101,
+			},
+			expErr: telegram.NewValidationError(
+				"MaxConnections",
+				"should be between 1 and 100",
+			),
+		},
+		{
+			cfg: telegram.WebhookCfg{
+				URL:         "https://example.com/hook",
+				SecretToken: "bad token",
+			},
+			expErr: telegram.NewValidationError(
+				"SecretToken",
+				"should be 1-256 characters of A-Z, a-z, 0-9, _ and -",
+			),
+		},
+	}
+	for i, tt := range testTable {
+		t.Logf("test #%d", i)
+		values, err := tt.cfg.Values()
+		assert.Equal(t, tt.expErr, err)
+		assert.Equal(t, tt.exp, values)
+	}
+}
+
+func TestDeleteWebhookCfg(t *testing.T) {
+	c := telegram.DeleteWebhookCfg{}
+	assert.Equal(t, "deleteWebhook", c.Name(), "method Name() has wrong value")
+	values, err := c.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{}, values)
+
+	c.DropPendingUpdates = true
+	values, err = c.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{"drop_pending_updates": {"true"}}, values)
+}
+
+func TestWebhookInfoCfg(t *testing.T) {
+	c := telegram.WebhookInfoCfg{}
+	assert.Equal(t, "getWebhookInfo", c.Name(), "method Name() has wrong value")
+	values, err := c.Values()
+	assert.Nil(t, values)
+	assert.NoError(t, err)
+}
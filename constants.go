@@ -56,6 +56,8 @@ const (
 
 	answerCallbackQueryMethod = "answerCallbackQuery"
 	setWebhookMethod          = "setWebhook"
+	deleteWebhookMethod       = "deleteWebhook"
+	getWebhookInfoMethod      = "getWebhookInfo"
 	getFileMethod             = "getFile"
 	answerInlineQueryMethod   = "answerInlineQuery"
 
@@ -76,8 +78,9 @@ const (
 
 // Constant values for ParseMode in MessageCfg.
 const (
-	MarkdownMode = "Markdown"
-	HTMLMode     = "HTML"
+	MarkdownMode   = "Markdown"
+	MarkdownV2Mode = "MarkdownV2"
+	HTMLMode       = "HTML"
 )
 
 // EntityType constants helps to set type of entity in MessageEntity object
@@ -85,15 +88,19 @@ const (
 	// @username
 	MentionEntityType     = "mention"
 	HashTagEntityType     = "hashtag"
+	CashTagEntityType     = "cashtag" // $USD
 	BotCommandEntityType  = "bot_command"
 	URLEntityType         = "url"
 	EmailEntityType       = "email"
-	BoldEntityType        = "bold"         // bold text
-	ItalicEntityType      = "italic"       // italic text
-	CodeEntityType        = "code"         // monowidth string
-	PreEntityType         = "pre"          // monowidth block
-	TextLinkEntityType    = "text_link"    // for clickable text URLs
-	TextMentionEntityType = "text_mention" // for users without usernames
+	BoldEntityType        = "bold"          // bold text
+	ItalicEntityType      = "italic"        // italic text
+	UnderlineEntityType   = "underline"     // underlined text
+	StrikeEntityType      = "strikethrough" // strikethrough text
+	CodeEntityType        = "code"          // monowidth string
+	PreEntityType         = "pre"           // monowidth block
+	TextLinkEntityType    = "text_link"     // for clickable text URLs
+	TextMentionEntityType = "text_mention"  // for users without usernames
+	SpoilerEntityType     = "spoiler"       // hidden text
 )
 
 // ChatMember possible statuses
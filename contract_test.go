@@ -0,0 +1,241 @@
+package telegram_test
+
+// contract_test checks types against recorded Bot API responses.
+//
+// Every testdata/contract/<Type>/*.json file is decoded into Type,
+// encoded back and compared with the original document.
+// A changed value is always an error. A key that is lost
+// after decoding (unknown to Type) is an error unless it's listed
+// in testdata/contract/known_gaps.txt as "<Type>/<file>.json <path>".
+// Extra fields are cleared before encoding, so keys kept
+// only in Extra are reported as lost too.
+//
+// Run with -contract.unknown to print all unknown keys.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bot-api/telegram"
+)
+
+var contractUnknown = flag.Bool("contract.unknown", false,
+	"print json keys of contract corpus unknown to types")
+
+const contractDir = "testdata/contract"
+
+// contractTypes are update and result types checked by corpus.
+// Every type must have a directory in corpus and vice versa.
+var contractTypes = map[string]func() interface{}{
+	"APIResponse":        func() interface{} { return &telegram.APIResponse{} },
+	"Update":             func() interface{} { return &telegram.Update{} },
+	"Message":            func() interface{} { return &telegram.Message{} },
+	"User":               func() interface{} { return &telegram.User{} },
+	"Chat":               func() interface{} { return &telegram.Chat{} },
+	"ChatMember":         func() interface{} { return &telegram.ChatMember{} },
+	"File":               func() interface{} { return &telegram.File{} },
+	"UserProfilePhotos":  func() interface{} { return &telegram.UserProfilePhotos{} },
+	"WebhookInfo":        func() interface{} { return &telegram.WebhookInfo{} },
+	"CallbackQuery":      func() interface{} { return &telegram.CallbackQuery{} },
+	"InlineQuery":        func() interface{} { return &telegram.InlineQuery{} },
+	"ChosenInlineResult": func() interface{} { return &telegram.ChosenInlineResult{} },
+}
+
+func TestContract(t *testing.T) {
+	gaps, err := readKnownGaps(filepath.Join(contractDir, "known_gaps.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs, err := ioutil.ReadDir(contractDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		name := dir.Name()
+		found[name] = true
+		newValue, ok := contractTypes[name]
+		if !ok {
+			t.Errorf("%s: no type is registered for corpus directory", name)
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(contractDir, name, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Errorf("%s: corpus directory is empty", name)
+		}
+		for _, file := range files {
+			key := name + "/" + filepath.Base(file)
+			lost, err := checkContract(file, newValue)
+			if err != nil {
+				t.Errorf("%s: %s", key, err.Error())
+				continue
+			}
+			for _, path := range lost {
+				if *contractUnknown {
+					t.Logf("%s: unknown key %s", key, path)
+				}
+				if !gaps[key][path] {
+					t.Errorf("%s: key %s is lost after decoding", key, path)
+				}
+				delete(gaps[key], path)
+			}
+		}
+	}
+	for name := range contractTypes {
+		if !found[name] {
+			t.Errorf("%s: type has no corpus directory", name)
+		}
+	}
+	for key, paths := range gaps {
+		for path := range paths {
+			t.Errorf("%s: key %s isn't lost anymore, "+
+				"remove it from known_gaps.txt", key, path)
+		}
+	}
+}
+
+// checkContract decodes file into a value, encodes it back
+// and returns paths of keys that are lost.
+func checkContract(file string, newValue func() interface{}) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	value := newValue()
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, fmt.Errorf("decode: %s", err.Error())
+	}
+	clearExtra(reflect.ValueOf(value))
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("encode: %s", err.Error())
+	}
+	var original, actual interface{}
+	if err := decodeGeneric(data, &original); err != nil {
+		return nil, err
+	}
+	if err := decodeGeneric(encoded, &actual); err != nil {
+		return nil, err
+	}
+	var lost, changed []string
+	compareJSON("$", original, actual, &lost, &changed)
+	if len(changed) > 0 {
+		return nil, fmt.Errorf("values are changed after decoding: %s",
+			strings.Join(changed, ", "))
+	}
+	sort.Strings(lost)
+	return lost, nil
+}
+
+var extraFieldsType = reflect.TypeOf((*telegram.ExtraFields)(nil))
+
+// clearExtra sets all Extra fields of v and its nested values to nil.
+func clearExtra(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearExtra(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			clearExtra(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if field.Type() == extraFieldsType {
+				field.Set(reflect.Zero(extraFieldsType))
+				continue
+			}
+			clearExtra(field)
+		}
+	}
+}
+
+func decodeGeneric(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// compareJSON collects keys of expected objects that are missing
+// in actual ones and values that differ.
+// Keys that are only in actual objects are ignored,
+// they are zero values of fields without omitempty.
+func compareJSON(path string, expected, actual interface{}, lost, changed *[]string) {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			*changed = append(*changed, path)
+			return
+		}
+		for key, value := range exp {
+			actValue, ok := act[key]
+			if !ok {
+				*lost = append(*lost, path+"."+key)
+				continue
+			}
+			compareJSON(path+"."+key, value, actValue, lost, changed)
+		}
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok || len(act) != len(exp) {
+			*changed = append(*changed, path)
+			return
+		}
+		for i := range exp {
+			compareJSON(fmt.Sprintf("%s[%d]", path, i), exp[i], act[i], lost, changed)
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			*changed = append(*changed, path)
+		}
+	}
+}
+
+// readKnownGaps reads lines "<Type>/<file>.json <path>".
+// Empty lines and lines started with # are skipped.
+func readKnownGaps(file string) (map[string]map[string]bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gaps := map[string]map[string]bool{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: bad line %q", file, line)
+		}
+		if gaps[fields[0]] == nil {
+			gaps[fields[0]] = map[string]bool{}
+		}
+		gaps[fields[0]][fields[1]] = true
+	}
+	return gaps, s.Err()
+}
@@ -0,0 +1,100 @@
+package telegram
+
+import (
+	"unicode/utf16"
+)
+
+// EntityText returns a part of text that entity refers to.
+// Entity offset and length are measured in UTF-16 code units,
+// so text with emoji and other characters outside the BMP
+// is handled correctly. Empty string is returned if entity
+// is out of text bounds.
+func EntityText(text string, e MessageEntity) string {
+	units := utf16.Encode([]rune(text))
+	if e.Offset < 0 || e.Length < 0 || e.Offset+e.Length > len(units) {
+		return ""
+	}
+	return string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+}
+
+// EntityText returns a part of message text that entity refers to.
+func (m *Message) EntityText(e MessageEntity) string {
+	return EntityText(m.Text, e)
+}
+
+// CaptionEntityText returns a part of message caption
+// that caption entity refers to.
+func (m *Message) CaptionEntityText(e MessageEntity) string {
+	return EntityText(m.Caption, e)
+}
+
+// Mentions returns all @username mentions of message text and caption.
+func (m *Message) Mentions() []string {
+	return m.entityTexts(MentionEntityType)
+}
+
+// HashTags returns all #hashtags of message text and caption.
+func (m *Message) HashTags() []string {
+	return m.entityTexts(HashTagEntityType)
+}
+
+// CashTags returns all $USD cashtags of message text and caption.
+func (m *Message) CashTags() []string {
+	return m.entityTexts(CashTagEntityType)
+}
+
+// BotCommands returns all /commands of message text and caption,
+// including the leading one, as they're written, e.x. /start@bot.
+func (m *Message) BotCommands() []string {
+	return m.entityTexts(BotCommandEntityType)
+}
+
+// URLs returns all urls of message text and caption.
+// For text links, url that is opened by tap is returned.
+func (m *Message) URLs() []string {
+	var urls []string
+	m.eachEntity(func(text string, e MessageEntity) {
+		switch e.Type {
+		case URLEntityType:
+			urls = append(urls, EntityText(text, e))
+		case TextLinkEntityType:
+			urls = append(urls, e.URL)
+		}
+	})
+	return urls
+}
+
+// TextMentions returns users mentioned in message text and caption,
+// that don't have usernames.
+func (m *Message) TextMentions() []User {
+	var users []User
+	m.eachEntity(func(_ string, e MessageEntity) {
+		if e.Type == TextMentionEntityType && e.User != nil {
+			users = append(users, *e.User)
+		}
+	})
+	return users
+}
+
+// ============== Internal ================================================== //
+
+// eachEntity calls fn for every text entity and then
+// for every caption entity.
+func (m *Message) eachEntity(fn func(text string, e MessageEntity)) {
+	for _, e := range m.Entities {
+		fn(m.Text, e)
+	}
+	for _, e := range m.CaptionEntities {
+		fn(m.Caption, e)
+	}
+}
+
+func (m *Message) entityTexts(entityType string) []string {
+	var texts []string
+	m.eachEntity(func(text string, e MessageEntity) {
+		if e.Type == entityType {
+			texts = append(texts, EntityText(text, e))
+		}
+	})
+	return texts
+}
@@ -0,0 +1,65 @@
+package telegram_test
+
+import (
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestEntityText(t *testing.T) {
+	text := "Привет 👋🏽 @john"
+	testTable := []struct {
+		entity telegram.MessageEntity
+		exp    string
+	}{
+		{telegram.MessageEntity{Offset: 0, Length: 6}, "Привет"},
+		// emoji with skin tone is 4 UTF-16 code units
+		{telegram.MessageEntity{Offset: 7, Length: 4}, "👋🏽"},
+		{telegram.MessageEntity{Offset: 12, Length: 5}, "@john"},
+		{telegram.MessageEntity{Offset: 12, Length: 6}, ""},
+		{telegram.MessageEntity{Offset: -1, Length: 2}, ""},
+		{telegram.MessageEntity{Offset: 17, Length: 0}, ""},
+	}
+	for i, tt := range testTable {
+		t.Logf("test #%d", i)
+		assert.Equal(t, tt.exp, telegram.EntityText(text, tt.entity))
+	}
+}
+
+func TestMessage_entities(t *testing.T) {
+	jane := telegram.User{ID: 2, FirstName: "Jane"}
+	msg := telegram.Message{
+		Text: "Привет 👋🏽 @john, see #golang and $USD at " +
+			"https://golang.org /help@test_bot",
+		Entities: []telegram.MessageEntity{
+			{Type: telegram.MentionEntityType, Offset: 12, Length: 5},
+			{Type: telegram.TextMentionEntityType, Offset: 19, Length: 3, User: &jane},
+			{Type: telegram.HashTagEntityType, Offset: 23, Length: 7},
+			{Type: telegram.CashTagEntityType, Offset: 35, Length: 4},
+			{Type: telegram.URLEntityType, Offset: 43, Length: 18},
+			{Type: telegram.BotCommandEntityType, Offset: 62, Length: 14},
+		},
+		Caption: "📷 by @jane #cats",
+		CaptionEntities: []telegram.MessageEntity{
+			{Type: telegram.MentionEntityType, Offset: 6, Length: 5},
+			{Type: telegram.HashTagEntityType, Offset: 12, Length: 5},
+			{Type: telegram.TextLinkEntityType, Offset: 0, Length: 2,
+				URL: "https://telegram.org/"},
+		},
+	}
+	assert.Equal(t, []string{"@john", "@jane"}, msg.Mentions())
+	assert.Equal(t, []string{"#golang", "#cats"}, msg.HashTags())
+	assert.Equal(t, []string{"$USD"}, msg.CashTags())
+	assert.Equal(t, []string{"/help@test_bot"}, msg.BotCommands())
+	assert.Equal(t, []string{"https://golang.org", "https://telegram.org/"},
+		msg.URLs())
+	assert.Equal(t, []telegram.User{jane}, msg.TextMentions())
+	assert.Equal(t, "see", msg.EntityText(msg.Entities[1]))
+	assert.Equal(t, "📷", msg.CaptionEntityText(msg.CaptionEntities[2]))
+
+	empty := telegram.Message{Text: "hello"}
+	assert.Nil(t, empty.Mentions())
+	assert.Nil(t, empty.URLs())
+	assert.Nil(t, empty.TextMentions())
+}
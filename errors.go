@@ -42,6 +42,9 @@ type APIError struct {
 	Description string `json:"description"`
 	// ErrorCode contents are subject to change in the future.
 	ErrorCode int `json:"error_code"`
+	// Parameters are set if the error can be handled automatically,
+	// e.x. RetryAfter is set for 429 Too Many Requests.
+	Parameters *ResponseParameters `json:"parameters,omitempty"`
 }
 
 // Error returns string representation for ApiError
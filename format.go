@@ -0,0 +1,241 @@
+package telegram
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// TextBuilder builds formatted message text from parts.
+// Every part is escaped, so user provided strings like names
+// can't break formatting. Zero value is ready to use:
+//
+//	b := new(telegram.TextBuilder).
+//		Plain("Hello, ").Mention(user.FirstName, user.ID).
+//		Plain("! Your code is ").Code(code)
+//	cfg := telegram.NewMessage(chatID, b.HTML())
+//	cfg.ParseMode = telegram.HTMLMode
+//
+// Or without a parse mode at all:
+//
+//	cfg := telegram.NewFormattedMessage(chatID, b)
+type TextBuilder struct {
+	parts []textPart
+}
+
+// Plain appends text without formatting.
+func (b *TextBuilder) Plain(text string) *TextBuilder {
+	return b.add(textPart{text: text})
+}
+
+// Bold appends bold text.
+func (b *TextBuilder) Bold(text string) *TextBuilder {
+	return b.add(textPart{entityType: BoldEntityType, text: text})
+}
+
+// Italic appends italic text.
+func (b *TextBuilder) Italic(text string) *TextBuilder {
+	return b.add(textPart{entityType: ItalicEntityType, text: text})
+}
+
+// Code appends monowidth string.
+func (b *TextBuilder) Code(text string) *TextBuilder {
+	return b.add(textPart{entityType: CodeEntityType, text: text})
+}
+
+// Pre appends monowidth block.
+// Language of the block is optional.
+func (b *TextBuilder) Pre(text, language string) *TextBuilder {
+	return b.add(textPart{
+		entityType: PreEntityType,
+		text:       text,
+		language:   language,
+	})
+}
+
+// Link appends text that opens url by tap.
+func (b *TextBuilder) Link(text, url string) *TextBuilder {
+	return b.add(textPart{
+		entityType: TextLinkEntityType,
+		text:       text,
+		url:        url,
+	})
+}
+
+// Mention appends text that mentions user with userID,
+// it works for users without usernames.
+func (b *TextBuilder) Mention(text string, userID int64) *TextBuilder {
+	return b.add(textPart{
+		entityType: TextMentionEntityType,
+		text:       text,
+		userID:     userID,
+	})
+}
+
+// Spoiler appends text that is hidden until tap.
+func (b *TextBuilder) Spoiler(text string) *TextBuilder {
+	return b.add(textPart{entityType: SpoilerEntityType, text: text})
+}
+
+// HTML returns text for HTMLMode parse mode.
+func (b *TextBuilder) HTML() string {
+	buf := bytes.Buffer{}
+	for _, p := range b.parts {
+		text := escapeHTML(p.text)
+		switch p.entityType {
+		case BoldEntityType:
+			buf.WriteString("<b>" + text + "</b>")
+		case ItalicEntityType:
+			buf.WriteString("<i>" + text + "</i>")
+		case CodeEntityType:
+			buf.WriteString("<code>" + text + "</code>")
+		case PreEntityType:
+			if p.language != "" {
+				buf.WriteString(`<pre><code class="language-` +
+					escapeHTML(p.language) + `">` + text + "</code></pre>")
+			} else {
+				buf.WriteString("<pre>" + text + "</pre>")
+			}
+		case TextLinkEntityType:
+			buf.WriteString(`<a href="` + escapeHTML(p.url) + `">` +
+				text + "</a>")
+		case TextMentionEntityType:
+			buf.WriteString(`<a href="` + userURL(p.userID) + `">` +
+				text + "</a>")
+		case SpoilerEntityType:
+			buf.WriteString("<tg-spoiler>" + text + "</tg-spoiler>")
+		default:
+			buf.WriteString(text)
+		}
+	}
+	return buf.String()
+}
+
+// MarkdownV2 returns text for MarkdownV2Mode parse mode.
+func (b *TextBuilder) MarkdownV2() string {
+	buf := bytes.Buffer{}
+	for i, p := range b.parts {
+		switch p.entityType {
+		case BoldEntityType:
+			buf.WriteString("*" + escapeMarkdownV2(p.text) + "*")
+		case ItalicEntityType:
+			if i > 0 && b.parts[i-1].entityType == ItalicEntityType {
+				// "__" is an underline, \r is ignored by telegram
+				buf.WriteString("\r")
+			}
+			buf.WriteString("_" + escapeMarkdownV2(p.text) + "_")
+		case CodeEntityType:
+			buf.WriteString("`" + escapeMarkdownV2Code(p.text) + "`")
+		case PreEntityType:
+			buf.WriteString("```" + escapeMarkdownV2Code(p.language) + "\n" +
+				escapeMarkdownV2Code(p.text) + "\n```")
+		case TextLinkEntityType:
+			buf.WriteString("[" + escapeMarkdownV2(p.text) + "](" +
+				escapeMarkdownV2URL(p.url) + ")")
+		case TextMentionEntityType:
+			buf.WriteString("[" + escapeMarkdownV2(p.text) + "](" +
+				userURL(p.userID) + ")")
+		case SpoilerEntityType:
+			buf.WriteString("||" + escapeMarkdownV2(p.text) + "||")
+		default:
+			buf.WriteString(escapeMarkdownV2(p.text))
+		}
+	}
+	return buf.String()
+}
+
+// Entities returns plain text and its entities,
+// that can be sent without a parse mode.
+func (b *TextBuilder) Entities() (string, []MessageEntity) {
+	buf := bytes.Buffer{}
+	var entities []MessageEntity
+	offset := 0
+	for _, p := range b.parts {
+		buf.WriteString(p.text)
+		length := len(utf16.Encode([]rune(p.text)))
+		if p.entityType != "" {
+			e := MessageEntity{
+				Type:     p.entityType,
+				Offset:   offset,
+				Length:   length,
+				URL:      p.url,
+				Language: p.language,
+			}
+			if p.entityType == TextMentionEntityType {
+				e.User = &User{ID: p.userID}
+			}
+			entities = append(entities, e)
+		}
+		offset += length
+	}
+	return buf.String(), entities
+}
+
+// NewFormattedMessage creates a new Message with text and entities of b.
+func NewFormattedMessage(chatID int64, b *TextBuilder) MessageCfg {
+	cfg := NewMessage(chatID, "")
+	cfg.Text, cfg.Entities = b.Entities()
+	return cfg
+}
+
+// ============== Internal ================================================== //
+
+type textPart struct {
+	entityType string
+	text       string
+	url        string
+	language   string
+	userID     int64
+}
+
+// add appends non empty part, because empty entities
+// can't be parsed by telegram.
+func (b *TextBuilder) add(p textPart) *TextBuilder {
+	if p.text == "" {
+		return b
+	}
+	b.parts = append(b.parts, p)
+	return b
+}
+
+func userURL(userID int64) string {
+	return "tg://user?id=" + strconv.FormatInt(userID, 10)
+}
+
+var htmlReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+func escapeHTML(s string) string {
+	return htmlReplacer.Replace(s)
+}
+
+var markdownV2Replacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`,
+	"(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`", ">", `\>`,
+	"#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`,
+	"{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+// escapeMarkdownV2 escapes text outside of code and pre entities.
+func escapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
+}
+
+var markdownV2CodeReplacer = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// escapeMarkdownV2Code escapes text inside of code and pre entities.
+func escapeMarkdownV2Code(s string) string {
+	return markdownV2CodeReplacer.Replace(s)
+}
+
+var markdownV2URLReplacer = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// escapeMarkdownV2URL escapes url of inline link.
+func escapeMarkdownV2URL(s string) string {
+	return markdownV2URLReplacer.Replace(s)
+}
@@ -0,0 +1,93 @@
+package telegram_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func newTestText() *telegram.TextBuilder {
+	return new(telegram.TextBuilder).
+		Plain("Hi, ").
+		Mention("<Bob_*1.0*>", 42).
+		Plain("! ").
+		Bold("Total: 5 (+1)").
+		Plain(" ").
+		Italic("a_b").
+		Italic("c").
+		Bold("").
+		Plain(" ").
+		Code("x := `y` \\ z").
+		Plain(" ").
+		Link("docs & [more]", "https://example.com/a_(b)?c=1&d=2").
+		Plain(" ").
+		Spoiler("👻 boo").
+		Pre("fmt.Println(\"<hi>\")", "go")
+}
+
+func TestTextBuilder_HTML(t *testing.T) {
+	assert.Equal(t,
+		`Hi, <a href="tg://user?id=42">&lt;Bob_*1.0*&gt;</a>! `+
+			`<b>Total: 5 (+1)</b> <i>a_b</i><i>c</i> `+
+			"<code>x := `y` \\ z</code> "+
+			`<a href="https://example.com/a_(b)?c=1&amp;d=2">`+
+			`docs &amp; [more]</a> <tg-spoiler>👻 boo</tg-spoiler>`+
+			`<pre><code class="language-go">fmt.Println(&quot;&lt;hi&gt;&quot;)`+
+			`</code></pre>`,
+		newTestText().HTML())
+	assert.Equal(t, "<pre>&amp;</pre>",
+		new(telegram.TextBuilder).Pre("&", "").HTML())
+}
+
+func TestTextBuilder_MarkdownV2(t *testing.T) {
+	assert.Equal(t,
+		`Hi, [<Bob\_\*1\.0\*\>](tg://user?id=42)\! `+
+			`*Total: 5 \(\+1\)* _a\_b_`+"\r"+`_c_ `+
+			"`x := \\`y\\` \\\\ z` "+
+			`[docs & \[more\]](https://example.com/a_(b\)?c=1&d=2) `+
+			`||👻 boo||`+
+			"```go\nfmt.Println(\"<hi>\")\n```",
+		newTestText().MarkdownV2())
+}
+
+func TestTextBuilder_Entities(t *testing.T) {
+	text, entities := newTestText().Entities()
+	assert.Equal(t, "Hi, <Bob_*1.0*>! Total: 5 (+1) a_bc x := `y` \\ z "+
+		"docs & [more] 👻 boofmt.Println(\"<hi>\")", text)
+	assert.Equal(t, []telegram.MessageEntity{
+		{Type: telegram.TextMentionEntityType, Offset: 4, Length: 11,
+			User: &telegram.User{ID: 42}},
+		{Type: telegram.BoldEntityType, Offset: 17, Length: 13},
+		{Type: telegram.ItalicEntityType, Offset: 31, Length: 3},
+		{Type: telegram.ItalicEntityType, Offset: 34, Length: 1},
+		{Type: telegram.CodeEntityType, Offset: 36, Length: 12},
+		{Type: telegram.TextLinkEntityType, Offset: 49, Length: 13,
+			URL: "https://example.com/a_(b)?c=1&d=2"},
+		// ghost emoji is 2 UTF-16 code units
+		{Type: telegram.SpoilerEntityType, Offset: 63, Length: 6},
+		{Type: telegram.PreEntityType, Offset: 69, Length: 19,
+			Language: "go"},
+	}, entities)
+	assert.Equal(t, "<Bob_*1.0*>", telegram.EntityText(text, entities[0]))
+	assert.Equal(t, "👻 boo", telegram.EntityText(text, entities[6]))
+	assert.Equal(t, "fmt.Println(\"<hi>\")", telegram.EntityText(text, entities[7]))
+
+	text, entities = new(telegram.TextBuilder).Entities()
+	assert.Equal(t, "", text)
+	assert.Nil(t, entities)
+}
+
+func TestNewFormattedMessage(t *testing.T) {
+	cfg := telegram.NewFormattedMessage(10,
+		new(telegram.TextBuilder).Plain("a ").Bold("b"))
+	v, err := cfg.Values()
+	require.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"chat_id":  {"10"},
+		"text":     {"a b"},
+		"entities": {`[{"type":"bold","offset":2,"length":1}]`},
+	}, v)
+}
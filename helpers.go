@@ -3,6 +3,7 @@ package telegram
 import (
 	"fmt"
 	"net/url"
+	"time"
 
 	"golang.org/x/net/context"
 )
@@ -267,6 +268,7 @@ func NewWebhookWithCert(link string, file InputFile) WebhookCfg {
 // CloneMessage convert message to Messenger type to send it to another chat.
 // It supports only data message: Text, Sticker, Audio, Photo, Location,
 // Contact, Audio, Voice, Document.
+// Text formatting is kept by entities.
 func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 	var base BaseMessage
 	if baseMessage == nil {
@@ -279,6 +281,7 @@ func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 		return &MessageCfg{
 			BaseMessage: base,
 			Text:        msg.Text,
+			Entities:    msg.Entities,
 		}
 	}
 	if msg.Sticker != nil {
@@ -295,7 +298,8 @@ func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 				BaseMessage: base,
 				FileID:      msg.Photo[len(msg.Photo)-1].FileID,
 			},
-			Caption: msg.Caption,
+			Caption:         msg.Caption,
+			CaptionEntities: msg.CaptionEntities,
 		}
 	}
 	if msg.Location != nil {
@@ -316,9 +320,22 @@ func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 				BaseMessage: base,
 				FileID:      msg.Audio.FileID,
 			},
-			Duration:  msg.Audio.Duration,
-			Performer: msg.Audio.Performer,
-			Title:     msg.Audio.Title,
+			Duration:        msg.Audio.Duration,
+			Performer:       msg.Audio.Performer,
+			Title:           msg.Audio.Title,
+			Caption:         msg.Caption,
+			CaptionEntities: msg.CaptionEntities,
+		}
+	}
+	if msg.Video != nil {
+		return &VideoCfg{
+			BaseFile: BaseFile{
+				BaseMessage: base,
+				FileID:      msg.Video.FileID,
+			},
+			Duration:        msg.Video.Duration,
+			Caption:         msg.Caption,
+			CaptionEntities: msg.CaptionEntities,
 		}
 	}
 	if msg.Voice != nil {
@@ -327,7 +344,9 @@ func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 				BaseMessage: base,
 				FileID:      msg.Voice.FileID,
 			},
-			Duration: msg.Voice.Duration,
+			Duration:        msg.Voice.Duration,
+			Caption:         msg.Caption,
+			CaptionEntities: msg.CaptionEntities,
 		}
 	}
 	if msg.Document != nil {
@@ -336,6 +355,8 @@ func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 				BaseMessage: base,
 				FileID:      msg.Document.FileID,
 			},
+			Caption:         msg.Caption,
+			CaptionEntities: msg.CaptionEntities,
 		}
 	}
 	return nil
@@ -344,38 +365,76 @@ func CloneMessage(msg *Message, baseMessage *BaseMessage) Messenger {
 // GetUpdates runs loop and requests updates from telegram.
 // It breaks loop, close out channel and returns error
 // if something happened during update cycle.
+// Use GetUpdatesWithRetry to retry failed requests.
 func GetUpdates(
 	ctx context.Context,
 	api *API,
 	cfg UpdateCfg,
 	out chan<- Update) error {
 
-	var rErr error
 	defer close(out)
+	return getUpdates(ctx, api, cfg, nil, sendUpdate(ctx, out))
+}
+
+// sendUpdate returns a func that sends update to out channel.
+func sendUpdate(ctx context.Context, out chan<- Update) func(Update) error {
+	return func(update Update) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- update:
+		}
+		return nil
+	}
+}
+
+// getUpdates runs update cycle and invokes fn for every update.
+// Failed requests are retried with backoff if it's not nil.
+func getUpdates(
+	ctx context.Context,
+	api *API,
+	cfg UpdateCfg,
+	b *backoff,
+	fn func(Update) error) error {
 
-loop:
 	for {
 		updates, err := api.GetUpdates(
 			ctx,
 			cfg,
 		)
 		if err != nil {
-			rErr = err
-			break loop
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if b == nil {
+				return err
+			}
+			delay, ok := b.next(err)
+			if !ok {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+		if b != nil {
+			b.reset()
 		}
 		for _, update := range updates {
 			if update.UpdateID >= cfg.Offset {
 				cfg.Offset = update.UpdateID + 1
-				select {
-				case <-ctx.Done():
-					rErr = ctx.Err()
-					break loop
-				case out <- update:
+				if err = fn(update); err != nil {
+					return err
 				}
 			}
 		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 	}
-	return rErr
 }
 
 // InlineQuery helpers
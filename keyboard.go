@@ -0,0 +1,447 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// MaxCallbackDataLength is a maximum length of callback data in bytes.
+const MaxCallbackDataLength = 64
+
+// StoredCallbackPrefix starts callback data that is a key
+// of data saved in CallbackDataStore. Don't use it in callback prefixes.
+const StoredCallbackPrefix = "~"
+
+// CallbackDataStore saves callback data longer than MaxCallbackDataLength.
+type CallbackDataStore interface {
+	// PutCallbackData saves data and returns a short key for it.
+	// Key must be shorter than MaxCallbackDataLength
+	// with StoredCallbackPrefix.
+	PutCallbackData(ctx context.Context, data string) (string, error)
+}
+
+// CallbackDataBatchStore is a CallbackDataStore that saves data
+// of several buttons at once. BuildWithStore uses it if store
+// implements it, so a keyboard is saved by one store write.
+type CallbackDataBatchStore interface {
+	CallbackDataStore
+	// PutCallbackDataBatch saves data and returns keys
+	// in the same order.
+	PutCallbackDataBatch(ctx context.Context, data []string) ([]string, error)
+}
+
+// InlineKeyboardBuilder builds inline keyboard markup.
+// Buttons are added to the current row, Row starts a new one:
+//
+//	markup, err := telegram.NewInlineKeyboardBuilder().
+//		Payload("👍", "vote", Vote{PostID: 10, Up: true}).
+//		Payload("👎", "vote", Vote{PostID: 10}).
+//		Row().
+//		URL("Open", "https://example.com/posts/10").
+//		Build()
+type InlineKeyboardBuilder struct {
+	rows [][]InlineKeyboardButton
+	row  []InlineKeyboardButton
+	err  error
+}
+
+// NewInlineKeyboardBuilder creates an empty InlineKeyboardBuilder.
+func NewInlineKeyboardBuilder() *InlineKeyboardBuilder {
+	return &InlineKeyboardBuilder{}
+}
+
+// Button adds button to the current row.
+func (b *InlineKeyboardBuilder) Button(button InlineKeyboardButton) *InlineKeyboardBuilder {
+	b.row = append(b.row, button)
+	return b
+}
+
+// Callback adds button that sends callback query with data.
+func (b *InlineKeyboardBuilder) Callback(text, data string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{Text: text, CallbackData: data})
+}
+
+// Payload adds button that sends callback query with payload
+// encoded by EncodeCallbackData. Encoding error is returned by Build.
+func (b *InlineKeyboardBuilder) Payload(text, prefix string, payload interface{}) *InlineKeyboardBuilder {
+	data, err := EncodeCallbackData(prefix, payload)
+	if err != nil && len(data) <= MaxCallbackDataLength && b.err == nil {
+		// long data is checked by Build
+		b.err = err
+	}
+	return b.Callback(text, data)
+}
+
+// URL adds button that opens url.
+func (b *InlineKeyboardBuilder) URL(text, url string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{Text: text, URL: url})
+}
+
+// SwitchInline adds button that inserts bot's username and query
+// to the input field of a chat selected by user.
+// Query can be empty.
+func (b *InlineKeyboardBuilder) SwitchInline(text, query string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{
+		Text:              text,
+		SwitchInlineQuery: query,
+		switchEmpty:       query == "",
+	})
+}
+
+// SwitchInlineCurrentChat adds button that inserts bot's username
+// and query to the input field of the current chat.
+// Query can be empty.
+func (b *InlineKeyboardBuilder) SwitchInlineCurrentChat(text, query string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{
+		Text:                         text,
+		SwitchInlineQueryCurrentChat: query,
+		switchCurrentChatEmpty:       query == "",
+	})
+}
+
+// Login adds button that authorizes user by login url.
+func (b *InlineKeyboardBuilder) Login(text string, login LoginURL) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{Text: text, LoginURL: &login})
+}
+
+// Pay adds pay button. It must be the first button in the first row.
+func (b *InlineKeyboardBuilder) Pay(text string) *InlineKeyboardBuilder {
+	return b.Button(InlineKeyboardButton{Text: text, Pay: true})
+}
+
+// Row finishes the current row, next buttons are added to a new row.
+func (b *InlineKeyboardBuilder) Row() *InlineKeyboardBuilder {
+	if len(b.row) > 0 {
+		b.rows = append(b.rows, b.row)
+		b.row = nil
+	}
+	return b
+}
+
+// Grid lays out buttons of the current row into rows
+// with columns buttons each and finishes them.
+func (b *InlineKeyboardBuilder) Grid(columns int) *InlineKeyboardBuilder {
+	if columns <= 0 {
+		return b.Row()
+	}
+	for len(b.row) > columns {
+		b.rows = append(b.rows, b.row[:columns:columns])
+		b.row = b.row[columns:]
+	}
+	return b.Row()
+}
+
+// Build finishes the current row and returns keyboard markup.
+// ValidationError is returned if callback data of any button
+// is longer than MaxCallbackDataLength or payload can't be encoded.
+func (b *InlineKeyboardBuilder) Build() (*InlineKeyboardMarkup, error) {
+	b.Row()
+	if b.err != nil {
+		return nil, b.err
+	}
+	for _, row := range b.rows {
+		for _, button := range row {
+			if err := checkCallbackData(button.CallbackData); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &InlineKeyboardMarkup{InlineKeyboard: b.rows}, nil
+}
+
+// BuildWithStore is like Build, but callback data longer than
+// MaxCallbackDataLength is saved to store and replaced
+// by StoredCallbackPrefix and a key returned by store.
+// telebot.Callbacks middleware resolves such data back.
+// Data of all buttons is saved by one call
+// if store implements CallbackDataBatchStore.
+func (b *InlineKeyboardBuilder) BuildWithStore(ctx context.Context, store CallbackDataStore) (*InlineKeyboardMarkup, error) {
+	b.Row()
+	if b.err != nil {
+		return nil, b.err
+	}
+	var long []*InlineKeyboardButton
+	var data []string
+	for _, row := range b.rows {
+		for i := range row {
+			if len(row[i].CallbackData) > MaxCallbackDataLength {
+				long = append(long, &row[i])
+				data = append(data, row[i].CallbackData)
+			}
+		}
+	}
+	keys, err := putCallbackData(ctx, store, data)
+	if err != nil {
+		return nil, err
+	}
+	for i, button := range long {
+		button.CallbackData = StoredCallbackPrefix + keys[i]
+	}
+	return b.Build()
+}
+
+// MaxInputFieldPlaceholderLength is a maximum length
+// of input field placeholder in characters.
+const MaxInputFieldPlaceholderLength = 64
+
+// ReplyKeyboardBuilder builds reply keyboard markup.
+// Buttons are added to the current row, Row starts a new one:
+//
+//	markup, err := telegram.NewReplyKeyboardBuilder().
+//		Text("Settings").Text("Help").
+//		Row().
+//		Contact("Share phone").
+//		Resize().
+//		Placeholder("Choose an option").
+//		Build()
+type ReplyKeyboardBuilder struct {
+	markup ReplyKeyboardMarkup
+	row    []KeyboardButton
+}
+
+// NewReplyKeyboardBuilder creates an empty ReplyKeyboardBuilder.
+func NewReplyKeyboardBuilder() *ReplyKeyboardBuilder {
+	return &ReplyKeyboardBuilder{}
+}
+
+// Button adds button to the current row.
+func (b *ReplyKeyboardBuilder) Button(button KeyboardButton) *ReplyKeyboardBuilder {
+	b.row = append(b.row, button)
+	return b
+}
+
+// Text adds button that sends its text as a message.
+func (b *ReplyKeyboardBuilder) Text(text string) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text})
+}
+
+// Contact adds button that sends user's phone number as a contact.
+func (b *ReplyKeyboardBuilder) Contact(text string) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text, RequestContact: true})
+}
+
+// Location adds button that sends user's current location.
+func (b *ReplyKeyboardBuilder) Location(text string) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text, RequestLocation: true})
+}
+
+// Poll adds button that asks user to create a poll and send it.
+// pollType is "quiz", "regular" or empty for any type.
+func (b *ReplyKeyboardBuilder) Poll(text, pollType string) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{
+		Text:        text,
+		RequestPoll: &KeyboardButtonPollType{Type: pollType},
+	})
+}
+
+// WebApp adds button that launches Web App with url.
+func (b *ReplyKeyboardBuilder) WebApp(text, url string) *ReplyKeyboardBuilder {
+	return b.Button(KeyboardButton{Text: text, WebApp: &WebAppInfo{URL: url}})
+}
+
+// Row finishes the current row, next buttons are added to a new row.
+func (b *ReplyKeyboardBuilder) Row() *ReplyKeyboardBuilder {
+	if len(b.row) > 0 {
+		b.markup.Keyboard = append(b.markup.Keyboard, b.row)
+		b.row = nil
+	}
+	return b
+}
+
+// Grid lays out buttons of the current row into rows
+// with columns buttons each and finishes them.
+func (b *ReplyKeyboardBuilder) Grid(columns int) *ReplyKeyboardBuilder {
+	if columns <= 0 {
+		return b.Row()
+	}
+	for len(b.row) > columns {
+		b.markup.Keyboard = append(b.markup.Keyboard, b.row[:columns:columns])
+		b.row = b.row[columns:]
+	}
+	return b.Row()
+}
+
+// Resize requests clients to fit keyboard height to its buttons.
+func (b *ReplyKeyboardBuilder) Resize() *ReplyKeyboardBuilder {
+	b.markup.ResizeKeyboard = true
+	return b
+}
+
+// OneTime requests clients to hide keyboard after it's used.
+func (b *ReplyKeyboardBuilder) OneTime() *ReplyKeyboardBuilder {
+	b.markup.OneTimeKeyboard = true
+	return b
+}
+
+// Persistent requests clients to always show keyboard.
+func (b *ReplyKeyboardBuilder) Persistent() *ReplyKeyboardBuilder {
+	b.markup.IsPersistent = true
+	return b
+}
+
+// Placeholder sets text shown in the input field
+// when keyboard is active.
+func (b *ReplyKeyboardBuilder) Placeholder(text string) *ReplyKeyboardBuilder {
+	b.markup.InputFieldPlaceholder = text
+	return b
+}
+
+// Selective shows keyboard only to mentioned users
+// and sender of the replied message.
+func (b *ReplyKeyboardBuilder) Selective() *ReplyKeyboardBuilder {
+	b.markup.Selective = true
+	return b
+}
+
+// Build finishes the current row and returns keyboard markup.
+// ValidationError is returned if keyboard has no buttons,
+// a button has no text or placeholder is longer than
+// MaxInputFieldPlaceholderLength.
+func (b *ReplyKeyboardBuilder) Build() (*ReplyKeyboardMarkup, error) {
+	b.Row()
+	if len(b.markup.Keyboard) == 0 {
+		return nil, NewValidationError("keyboard", "no buttons")
+	}
+	for _, row := range b.markup.Keyboard {
+		for _, button := range row {
+			if button.Text == "" {
+				return nil, NewValidationError("keyboard", "button without text")
+			}
+		}
+	}
+	length := utf8.RuneCountInString(b.markup.InputFieldPlaceholder)
+	if length > MaxInputFieldPlaceholderLength {
+		return nil, NewValidationError("input_field_placeholder", fmt.Sprintf(
+			"%d characters is longer than %d characters",
+			length, MaxInputFieldPlaceholderLength))
+	}
+	markup := b.markup
+	return &markup, nil
+}
+
+// NewReplyKeyboardRemove returns markup that removes reply keyboard,
+// selective removes it only for mentioned users
+// and sender of the replied message.
+func NewReplyKeyboardRemove(selective bool) ReplyKeyboardRemove {
+	return ReplyKeyboardRemove{
+		RemoveKeyboard: true,
+		Selective:      selective,
+	}
+}
+
+// EncodeCallbackData returns callback data "prefix:payload",
+// it's routed by prefix in telebot.Callbacks middleware.
+// Struct payload is encoded as json array of its exported
+// field values in order of declaration to fit the limit,
+// e.x. Vote{PostID: 10, Up: true} is encoded as [10,true],
+// so changing fields order breaks buttons of sent messages.
+// Other payloads are encoded as json values, nil payload is omitted.
+// ValidationError is returned if data is longer than
+// MaxCallbackDataLength.
+func EncodeCallbackData(prefix string, payload interface{}) (string, error) {
+	if payload == nil {
+		return prefix, checkCallbackData(prefix)
+	}
+	value := reflect.ValueOf(payload)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	var data []byte
+	var err error
+	if value.Kind() == reflect.Struct {
+		var fields []interface{}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).PkgPath == "" {
+				fields = append(fields, value.Field(i).Interface())
+			}
+		}
+		data, err = json.Marshal(fields)
+	} else {
+		data, err = json.Marshal(payload)
+	}
+	if err != nil {
+		return "", NewValidationError("callback_data", err.Error())
+	}
+	result := prefix + ":" + string(data)
+	return result, checkCallbackData(result)
+}
+
+// DecodeCallbackData decodes payload encoded by EncodeCallbackData,
+// data is a part of callback data after prefix and ":".
+// payload is a pointer to value of the same type as encoded one.
+func DecodeCallbackData(data string, payload interface{}) error {
+	ptr := reflect.ValueOf(payload)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("payload must be a non nil pointer, got %T", payload)
+	}
+	value := ptr.Elem()
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return json.Unmarshal([]byte(data), payload)
+	}
+	var fields []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return err
+	}
+	n := 0
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		if n >= len(fields) {
+			// fields added after button was sent keep zero values
+			break
+		}
+		err := json.Unmarshal(fields[n], value.Field(i).Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("field %s: %s",
+				value.Type().Field(i).Name, err.Error())
+		}
+		n++
+	}
+	return nil
+}
+
+// ============== Internal ================================================== //
+
+func checkCallbackData(data string) error {
+	if len(data) > MaxCallbackDataLength {
+		return NewValidationError("callback_data", fmt.Sprintf(
+			"%d bytes is longer than %d bytes",
+			len(data), MaxCallbackDataLength))
+	}
+	return nil
+}
+
+// putCallbackData saves data to store and returns keys for it.
+func putCallbackData(ctx context.Context, store CallbackDataStore, data []string) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if batch, ok := store.(CallbackDataBatchStore); ok {
+		keys, err := batch.PutCallbackDataBatch(ctx, data)
+		if err == nil && len(keys) != len(data) {
+			err = fmt.Errorf("store returned %d keys for %d callbacks",
+				len(keys), len(data))
+		}
+		return keys, err
+	}
+	keys := make([]string, len(data))
+	for i := range data {
+		key, err := store.PutCallbackData(ctx, data[i])
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,299 @@
+package telegram_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+type vote struct {
+	PostID int64
+	Up     bool
+	hidden string
+}
+
+func TestInlineKeyboardBuilder(t *testing.T) {
+	markup, err := telegram.NewInlineKeyboardBuilder().
+		Pay("Pay").
+		Row().
+		Payload("Up", "vote", vote{PostID: 10, Up: true}).
+		Payload("Down", "vote", &vote{PostID: 10}).
+		Row().
+		Callback("1", "n:1").Callback("2", "n:2").Callback("3", "n:3").
+		Callback("4", "n:4").Callback("5", "n:5").
+		Grid(2).
+		URL("Open", "https://example.com").
+		SwitchInline("Share", "q").
+		SwitchInlineCurrentChat("Search", "").
+		Row().
+		Row().
+		Login("Login", telegram.LoginURL{URL: "https://example.com/login"}).
+		Build()
+	require.NoError(t, err)
+	// switch button with empty query is equal to a decoded one
+	var search telegram.InlineKeyboardButton
+	require.NoError(t, json.Unmarshal([]byte(
+		`{"text":"Search","switch_inline_query_current_chat":""}`), &search))
+	assert.Equal(t, &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{{Text: "Pay", Pay: true}},
+			{
+				{Text: "Up", CallbackData: "vote:[10,true]"},
+				{Text: "Down", CallbackData: "vote:[10,false]"},
+			},
+			{
+				{Text: "1", CallbackData: "n:1"},
+				{Text: "2", CallbackData: "n:2"},
+			},
+			{
+				{Text: "3", CallbackData: "n:3"},
+				{Text: "4", CallbackData: "n:4"},
+			},
+			{{Text: "5", CallbackData: "n:5"}},
+			{
+				{Text: "Open", URL: "https://example.com"},
+				{Text: "Share", SwitchInlineQuery: "q"},
+				search,
+			},
+			{{Text: "Login", LoginURL: &telegram.LoginURL{
+				URL: "https://example.com/login",
+			}}},
+		},
+	}, markup)
+
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Callback("long", strings.Repeat("a", 65)).
+		Build()
+	assert.True(t, telegram.IsValidationError(err))
+
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Payload("long", "p", strings.Repeat("a", 61)).
+		Payload("bad", "p", func() {}).
+		Build()
+	assert.True(t, telegram.IsValidationError(err))
+}
+
+func TestReplyKeyboardBuilder(t *testing.T) {
+	markup, err := telegram.NewReplyKeyboardBuilder().
+		Text("1").Text("2").Text("3").
+		Grid(2).
+		Contact("Phone").Location("Location").
+		Row().
+		Poll("Quiz", "quiz").WebApp("App", "https://example.com/app").
+		Resize().OneTime().Persistent().Selective().
+		Placeholder("Choose").
+		Build()
+	require.NoError(t, err)
+	data, err := json.Marshal(markup)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"keyboard": [
+			[{"text": "1"}, {"text": "2"}],
+			[{"text": "3"}],
+			[
+				{"text": "Phone", "request_contact": true},
+				{"text": "Location", "request_location": true}
+			],
+			[
+				{"text": "Quiz", "request_poll": {"type": "quiz"}},
+				{"text": "App", "web_app": {"url": "https://example.com/app"}}
+			]
+		],
+		"resize_keyboard": true,
+		"one_time_keyboard": true,
+		"is_persistent": true,
+		"input_field_placeholder": "Choose",
+		"selective": true
+	}`, string(data))
+
+	for _, b := range []*telegram.ReplyKeyboardBuilder{
+		telegram.NewReplyKeyboardBuilder(),
+		telegram.NewReplyKeyboardBuilder().Contact(""),
+		telegram.NewReplyKeyboardBuilder().Text("a").
+			Placeholder(strings.Repeat("ы", 65)),
+	} {
+		_, err = b.Build()
+		assert.True(t, telegram.IsValidationError(err))
+	}
+	_, err = telegram.NewReplyKeyboardBuilder().Text("a").
+		Placeholder(strings.Repeat("ы", 64)).Build()
+	assert.NoError(t, err)
+
+	data, err = json.Marshal(telegram.NewReplyKeyboardRemove(false))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"remove_keyboard": true}`, string(data))
+	data, err = json.Marshal(telegram.NewReplyKeyboardRemove(true))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"remove_keyboard": true, "selective": true}`, string(data))
+}
+
+type mapCallbackStore map[string]string
+
+func (s mapCallbackStore) PutCallbackData(_ context.Context, data string) (string, error) {
+	if strings.HasPrefix(data, "fail") {
+		return "", fmt.Errorf("store error")
+	}
+	key := fmt.Sprintf("k%d", len(s))
+	s[key] = data
+	return key, nil
+}
+
+// batchCallbackStore counts batch puts.
+type batchCallbackStore struct {
+	mapCallbackStore
+	batches int
+}
+
+func (s *batchCallbackStore) PutCallbackDataBatch(ctx context.Context, data []string) ([]string, error) {
+	s.batches++
+	var keys []string
+	for _, d := range data {
+		key, err := s.PutCallbackData(ctx, d)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func TestInlineKeyboardBuilder_BuildWithStore_batch(t *testing.T) {
+	ctx := context.Background()
+	store := &batchCallbackStore{mapCallbackStore: mapCallbackStore{}}
+	long := strings.Repeat("a", 70)
+	markup, err := telegram.NewInlineKeyboardBuilder().
+		Callback("first", "1"+long).
+		Callback("short", "n:1").
+		Row().
+		Callback("second", "2"+long).
+		BuildWithStore(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.batches)
+	assert.Equal(t, &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{
+			{
+				{Text: "first", CallbackData: telegram.StoredCallbackPrefix + "k0"},
+				{Text: "short", CallbackData: "n:1"},
+			},
+			{{Text: "second", CallbackData: telegram.StoredCallbackPrefix + "k1"}},
+		},
+	}, markup)
+
+	// store isn't called without long data
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Callback("short", "n:1").
+		BuildWithStore(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.batches)
+
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Callback("fail", "fail"+long).
+		BuildWithStore(ctx, store)
+	assert.EqualError(t, err, "store error")
+}
+
+func TestInlineKeyboardButton_switchEmpty(t *testing.T) {
+	markup, err := telegram.NewInlineKeyboardBuilder().
+		SwitchInline("Share", "").
+		SwitchInlineCurrentChat("Search", "").
+		SwitchInline("Query", "q").
+		Callback("Data", "d").
+		Build()
+	require.NoError(t, err)
+	data, err := json.Marshal(markup)
+	require.NoError(t, err)
+	expected := `{"inline_keyboard":[[
+		{"text":"Share","switch_inline_query":""},
+		{"text":"Search","switch_inline_query_current_chat":""},
+		{"text":"Query","switch_inline_query":"q"},
+		{"text":"Data","callback_data":"d"}
+	]]}`
+	assert.JSONEq(t, expected, string(data))
+
+	// empty queries survive decoding
+	decoded := telegram.InlineKeyboardMarkup{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, *markup, decoded)
+	data, err = json.Marshal(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(data))
+
+	// empty query of a button literal is omitted
+	data, err = json.Marshal(telegram.InlineKeyboardButton{Text: "Text"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text":"Text"}`, string(data))
+}
+
+func TestInlineKeyboardBuilder_BuildWithStore(t *testing.T) {
+	ctx := context.Background()
+	store := mapCallbackStore{}
+	long := strings.Repeat("a", 70)
+	markup, err := telegram.NewInlineKeyboardBuilder().
+		Callback("short", "n:1").
+		Payload("long", "p", long).
+		BuildWithStore(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{{
+			{Text: "short", CallbackData: "n:1"},
+			{Text: "long", CallbackData: telegram.StoredCallbackPrefix + "k0"},
+		}},
+	}, markup)
+	assert.Equal(t, mapCallbackStore{"k0": "p:\"" + long + "\""}, store)
+
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Payload("bad", "p", func() {}).
+		BuildWithStore(ctx, store)
+	assert.True(t, telegram.IsValidationError(err))
+
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Callback("fail", "fail"+long).
+		BuildWithStore(ctx, store)
+	assert.EqualError(t, err, "store error")
+
+	_, err = telegram.NewInlineKeyboardBuilder().
+		Callback("long key", long).
+		BuildWithStore(ctx, longKeyStore{})
+	assert.True(t, telegram.IsValidationError(err))
+}
+
+type longKeyStore struct{}
+
+func (longKeyStore) PutCallbackData(context.Context, string) (string, error) {
+	return strings.Repeat("k", 64), nil
+}
+
+func TestCallbackData(t *testing.T) {
+	data, err := telegram.EncodeCallbackData("p", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "p", data)
+
+	data, err = telegram.EncodeCallbackData("p", "text")
+	require.NoError(t, err)
+	assert.Equal(t, `p:"text"`, data)
+	var s string
+	require.NoError(t, telegram.DecodeCallbackData(`"text"`, &s))
+	assert.Equal(t, "text", s)
+
+	data, err = telegram.EncodeCallbackData("vote", vote{PostID: 7, Up: true, hidden: "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "vote:[7,true]", data)
+
+	v := vote{}
+	require.NoError(t, telegram.DecodeCallbackData("[7,true]", &v))
+	assert.Equal(t, vote{PostID: 7, Up: true}, v)
+
+	var pv *vote
+	require.NoError(t, telegram.DecodeCallbackData("[8]", &pv))
+	assert.Equal(t, &vote{PostID: 8}, pv)
+
+	assert.Error(t, telegram.DecodeCallbackData(`["x"]`, &v))
+	assert.Error(t, telegram.DecodeCallbackData(`{}`, &v))
+	assert.Error(t, telegram.DecodeCallbackData(`[1]`, v))
+}
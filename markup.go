@@ -0,0 +1,403 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/net/html"
+)
+
+// RenderHTML converts text and its entities to text for HTMLMode
+// parse mode. Nested and overlapping entities are supported,
+// overlapping ones are split into properly nested tags.
+// Entities that telegram detects automatically (mentions, hashtags,
+// urls, etc.) are rendered as plain text.
+func RenderHTML(text string, entities []MessageEntity) string {
+	return renderEntities(text, entities, htmlMarkup{})
+}
+
+// RenderMarkdownV2 converts text and its entities to text
+// for MarkdownV2Mode parse mode. See RenderHTML for details.
+func RenderMarkdownV2(text string, entities []MessageEntity) string {
+	return renderEntities(text, entities, markdownV2Markup{})
+}
+
+// HTML returns message text with formatting for HTMLMode parse mode.
+func (m *Message) HTML() string {
+	return RenderHTML(m.Text, m.Entities)
+}
+
+// MarkdownV2 returns message text with formatting
+// for MarkdownV2Mode parse mode.
+func (m *Message) MarkdownV2() string {
+	return RenderMarkdownV2(m.Text, m.Entities)
+}
+
+// ParseHTML converts text in HTMLMode parse mode to plain text
+// and its entities. Tags supported by telegram are parsed:
+// b, strong, i, em, u, ins, s, strike, del, code, pre,
+// a (tg://user?id= links are text mentions),
+// tg-spoiler and span class="tg-spoiler".
+// ValidationError is returned for other tags and broken markup.
+func ParseHTML(text string) (string, []MessageEntity, error) {
+	var (
+		buf      bytes.Buffer
+		entities []MessageEntity
+		stack    []htmlTag
+		offset   int
+	)
+	z := html.NewTokenizer(strings.NewReader(text))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return "", nil, z.Err()
+			}
+			if len(stack) > 0 {
+				return "", nil, htmlError("unclosed tag <%s>",
+					stack[len(stack)-1].name)
+			}
+			sort.Stable(entitiesByOffset(entities))
+			return buf.String(), entities, nil
+		case html.TextToken:
+			// text is unescaped by tokenizer
+			data := string(z.Text())
+			buf.WriteString(data)
+			offset += len(utf16.Encode([]rune(data)))
+		case html.StartTagToken:
+			tag, err := parseHTMLTag(z, stack, offset)
+			if err != nil {
+				return "", nil, err
+			}
+			stack = append(stack, tag)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if len(stack) == 0 || stack[len(stack)-1].name != string(name) {
+				return "", nil, htmlError("unexpected end tag </%s>", name)
+			}
+			tag := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if tag.entity.Type == "" || offset == tag.entity.Offset {
+				continue
+			}
+			tag.entity.Length = offset - tag.entity.Offset
+			entities = append(entities, tag.entity)
+		case html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			return "", nil, htmlError("unsupported tag <%s/>", name)
+		}
+	}
+}
+
+// ============== Internal ================================================== //
+
+type markup interface {
+	open(e MessageEntity) string
+	close(e MessageEntity) string
+	// escape escapes text, code is true inside code and pre entities.
+	escape(text string, code bool) string
+}
+
+type htmlMarkup struct{}
+
+func (htmlMarkup) open(e MessageEntity) string {
+	switch e.Type {
+	case BoldEntityType:
+		return "<b>"
+	case ItalicEntityType:
+		return "<i>"
+	case UnderlineEntityType:
+		return "<u>"
+	case StrikeEntityType:
+		return "<s>"
+	case SpoilerEntityType:
+		return "<tg-spoiler>"
+	case CodeEntityType:
+		return "<code>"
+	case PreEntityType:
+		if e.Language != "" {
+			return `<pre><code class="language-` +
+				escapeHTML(e.Language) + `">`
+		}
+		return "<pre>"
+	case TextLinkEntityType:
+		return `<a href="` + escapeHTML(e.URL) + `">`
+	case TextMentionEntityType:
+		return `<a href="` + userURL(e.User.ID) + `">`
+	}
+	return ""
+}
+
+func (htmlMarkup) close(e MessageEntity) string {
+	switch e.Type {
+	case BoldEntityType:
+		return "</b>"
+	case ItalicEntityType:
+		return "</i>"
+	case UnderlineEntityType:
+		return "</u>"
+	case StrikeEntityType:
+		return "</s>"
+	case SpoilerEntityType:
+		return "</tg-spoiler>"
+	case CodeEntityType:
+		return "</code>"
+	case PreEntityType:
+		if e.Language != "" {
+			return "</code></pre>"
+		}
+		return "</pre>"
+	case TextLinkEntityType, TextMentionEntityType:
+		return "</a>"
+	}
+	return ""
+}
+
+func (htmlMarkup) escape(text string, _ bool) string {
+	return escapeHTML(text)
+}
+
+type markdownV2Markup struct{}
+
+func (markdownV2Markup) open(e MessageEntity) string {
+	switch e.Type {
+	case PreEntityType:
+		return "```" + escapeMarkdownV2Code(e.Language) + "\n"
+	case TextLinkEntityType, TextMentionEntityType:
+		return "["
+	}
+	return markdownV2Markers[e.Type]
+}
+
+func (markdownV2Markup) close(e MessageEntity) string {
+	switch e.Type {
+	case PreEntityType:
+		return "\n```"
+	case TextLinkEntityType:
+		return "](" + escapeMarkdownV2URL(e.URL) + ")"
+	case TextMentionEntityType:
+		return "](" + userURL(e.User.ID) + ")"
+	}
+	return markdownV2Markers[e.Type]
+}
+
+func (markdownV2Markup) escape(text string, code bool) string {
+	if code {
+		return escapeMarkdownV2Code(text)
+	}
+	return escapeMarkdownV2(text)
+}
+
+var markdownV2Markers = map[string]string{
+	BoldEntityType:      "*",
+	ItalicEntityType:    "_",
+	UnderlineEntityType: "__",
+	StrikeEntityType:    "~",
+	SpoilerEntityType:   "||",
+	CodeEntityType:      "`",
+}
+
+// renderEntities writes text with markup of formatting entities.
+// Entity that overlaps an outer one is closed with it
+// and opened again right after.
+func renderEntities(text string, entities []MessageEntity, m markup) string {
+	units := utf16.Encode([]rune(text))
+	entities = formattingEntities(entities, len(units))
+
+	points := []int{0, len(units)}
+	for _, e := range entities {
+		points = append(points, e.Offset, e.Offset+e.Length)
+	}
+	sort.Ints(points)
+	unique := points[:1]
+	for _, pos := range points[1:] {
+		if pos != unique[len(unique)-1] {
+			unique = append(unique, pos)
+		}
+	}
+	points = unique
+
+	buf := bytes.Buffer{}
+	lastMarker := false
+	writeMarker := func(s string) {
+		if s == "" {
+			return
+		}
+		b := buf.Bytes()
+		if lastMarker && b[len(b)-1] == '_' && s[0] == '_' {
+			// "__" is an underline, \r is ignored by telegram
+			buf.WriteString("\r")
+		}
+		buf.WriteString(s)
+		lastMarker = true
+	}
+
+	var stack []MessageEntity
+	next := 0
+	for i, pos := range points {
+		// close entities that end here and all entities inside them
+		first := -1
+		for j, e := range stack {
+			if e.Offset+e.Length <= pos {
+				first = j
+				break
+			}
+		}
+		if first >= 0 {
+			for j := len(stack) - 1; j >= first; j-- {
+				writeMarker(m.close(stack[j]))
+			}
+			var reopen []MessageEntity
+			for _, e := range stack[first:] {
+				if e.Offset+e.Length > pos {
+					reopen = append(reopen, e)
+				}
+			}
+			stack = stack[:first]
+			for _, e := range reopen {
+				writeMarker(m.open(e))
+				stack = append(stack, e)
+			}
+		}
+		for next < len(entities) && entities[next].Offset == pos {
+			writeMarker(m.open(entities[next]))
+			stack = append(stack, entities[next])
+			next++
+		}
+		if i+1 < len(points) {
+			code := false
+			for _, e := range stack {
+				if e.Type == CodeEntityType || e.Type == PreEntityType {
+					code = true
+				}
+			}
+			buf.WriteString(m.escape(
+				string(utf16.Decode(units[pos:points[i+1]])), code))
+			lastMarker = false
+		}
+	}
+	return buf.String()
+}
+
+// formattingEntities returns entities that have markup,
+// cut to text length and sorted from outer to inner ones.
+func formattingEntities(entities []MessageEntity, length int) []MessageEntity {
+	result := make([]MessageEntity, 0, len(entities))
+	for _, e := range entities {
+		if e.Type == TextMentionEntityType && e.User == nil {
+			continue
+		}
+		if _, ok := markdownV2Markers[e.Type]; !ok &&
+			e.Type != PreEntityType &&
+			e.Type != TextLinkEntityType &&
+			e.Type != TextMentionEntityType {
+			continue
+		}
+		if e.Offset < 0 || e.Offset >= length || e.Length <= 0 {
+			continue
+		}
+		if e.Offset+e.Length > length {
+			e.Length = length - e.Offset
+		}
+		result = append(result, e)
+	}
+	sort.Stable(entitiesByOffset(result))
+	return result
+}
+
+// entitiesByOffset sorts entities by offset, longer entities go first.
+type entitiesByOffset []MessageEntity
+
+func (s entitiesByOffset) Len() int      { return len(s) }
+func (s entitiesByOffset) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s entitiesByOffset) Less(i, j int) bool {
+	if s[i].Offset != s[j].Offset {
+		return s[i].Offset < s[j].Offset
+	}
+	return s[i].Length > s[j].Length
+}
+
+type htmlTag struct {
+	name string
+	// entity is started by tag, its type is empty
+	// for tags without entity, e.x. <code> inside <pre>.
+	entity MessageEntity
+}
+
+var htmlEntityTypes = map[string]string{
+	"b":          BoldEntityType,
+	"strong":     BoldEntityType,
+	"i":          ItalicEntityType,
+	"em":         ItalicEntityType,
+	"u":          UnderlineEntityType,
+	"ins":        UnderlineEntityType,
+	"s":          StrikeEntityType,
+	"strike":     StrikeEntityType,
+	"del":        StrikeEntityType,
+	"code":       CodeEntityType,
+	"pre":        PreEntityType,
+	"tg-spoiler": SpoilerEntityType,
+}
+
+func parseHTMLTag(z *html.Tokenizer, stack []htmlTag, offset int) (htmlTag, error) {
+	name, hasAttr := z.TagName()
+	attrs := map[string]string{}
+	for hasAttr {
+		var key, value []byte
+		key, value, hasAttr = z.TagAttr()
+		attrs[string(key)] = string(value)
+	}
+	tag := htmlTag{
+		name:   string(name),
+		entity: MessageEntity{Offset: offset},
+	}
+	switch tag.name {
+	case "a":
+		href := attrs["href"]
+		if strings.HasPrefix(href, "tg://user?id=") {
+			id, err := strconv.ParseInt(
+				strings.TrimPrefix(href, "tg://user?id="), 10, 64)
+			if err != nil {
+				return tag, htmlError("bad user link %q", href)
+			}
+			tag.entity.Type = TextMentionEntityType
+			tag.entity.User = &User{ID: id}
+		} else {
+			tag.entity.Type = TextLinkEntityType
+			tag.entity.URL = href
+		}
+	case "span":
+		if attrs["class"] != "tg-spoiler" {
+			return tag, htmlError("unsupported tag <span>")
+		}
+		tag.entity.Type = SpoilerEntityType
+	case "code":
+		if len(stack) > 0 {
+			pre := &stack[len(stack)-1]
+			if pre.entity.Type == PreEntityType && pre.entity.Offset == offset {
+				// <pre><code class="language-go"> is a pre with language
+				pre.entity.Language = strings.TrimPrefix(
+					attrs["class"], "language-")
+				return tag, nil
+			}
+		}
+		tag.entity.Type = CodeEntityType
+	default:
+		entityType, ok := htmlEntityTypes[tag.name]
+		if !ok {
+			return tag, htmlError("unsupported tag <%s>", tag.name)
+		}
+		tag.entity.Type = entityType
+	}
+	return tag, nil
+}
+
+func htmlError(format string, args ...interface{}) error {
+	return NewValidationError("text", fmt.Sprintf(format, args...))
+}
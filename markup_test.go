@@ -0,0 +1,193 @@
+package telegram_test
+
+import (
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func entity(entityType string, offset, length int) telegram.MessageEntity {
+	return telegram.MessageEntity{
+		Type:   entityType,
+		Offset: offset,
+		Length: length,
+	}
+}
+
+func TestRender(t *testing.T) {
+	bob := &telegram.User{ID: 42, FirstName: "Bob"}
+	testTable := []struct {
+		text       string
+		entities   []telegram.MessageEntity
+		html       string
+		markdownV2 string
+	}{
+		{
+			text:       "1 < 2.",
+			html:       "1 &lt; 2.",
+			markdownV2: `1 < 2\.`,
+		},
+		// nested entities
+		{
+			text: "bold italic",
+			entities: []telegram.MessageEntity{
+				entity(telegram.ItalicEntityType, 5, 6),
+				entity(telegram.BoldEntityType, 0, 11),
+			},
+			html:       "<b>bold <i>italic</i></b>",
+			markdownV2: "*bold _italic_*",
+		},
+		// overlapping entities
+		{
+			text: "abcdef",
+			entities: []telegram.MessageEntity{
+				entity(telegram.BoldEntityType, 0, 4),
+				entity(telegram.StrikeEntityType, 2, 4),
+			},
+			html:       "<b>ab<s>cd</s></b><s>ef</s>",
+			markdownV2: "*ab~cd~*~ef~",
+		},
+		// offsets in UTF-16 code units
+		{
+			text: "👋 hi 👋",
+			entities: []telegram.MessageEntity{
+				entity(telegram.BoldEntityType, 3, 2),
+				entity(telegram.SpoilerEntityType, 6, 2),
+			},
+			html:       "👋 <b>hi</b> <tg-spoiler>👋</tg-spoiler>",
+			markdownV2: "👋 *hi* ||👋||",
+		},
+		// italic and underline
+		{
+			text: "a_b",
+			entities: []telegram.MessageEntity{
+				entity(telegram.ItalicEntityType, 0, 3),
+				entity(telegram.UnderlineEntityType, 0, 3),
+			},
+			html:       "<i><u>a_b</u></i>",
+			markdownV2: "_\r__a\\_b__\r_",
+		},
+		// links, code and automatic entities
+		{
+			text: "see docs, Bob: `x` @john\nfunc() {}",
+			entities: []telegram.MessageEntity{
+				{Type: telegram.TextLinkEntityType, Offset: 4, Length: 4,
+					URL: "http://a.b/c_(d)?e=1&f=2"},
+				{Type: telegram.TextMentionEntityType, Offset: 10, Length: 3,
+					User: bob},
+				entity(telegram.CodeEntityType, 15, 3),
+				entity(telegram.MentionEntityType, 19, 5),
+				{Type: telegram.PreEntityType, Offset: 25, Length: 9,
+					Language: "go"},
+				// out of text
+				entity(telegram.BoldEntityType, 40, 2),
+				// text mention without user
+				entity(telegram.TextMentionEntityType, 0, 3),
+			},
+			html: `see <a href="http://a.b/c_(d)?e=1&amp;f=2">docs</a>, ` +
+				`<a href="tg://user?id=42">Bob</a>: <code>` + "`x`" + `</code> @john` + "\n" +
+				`<pre><code class="language-go">func() {}</code></pre>`,
+			markdownV2: `see [docs](http://a.b/c_(d\)?e=1&f=2), ` +
+				`[Bob](tg://user?id=42): ` + "`\\`x\\``" + ` @john` + "\n" +
+				"```go\nfunc() {}\n```",
+		},
+		// entity longer than text is cut
+		{
+			text: "abc",
+			entities: []telegram.MessageEntity{
+				entity(telegram.BoldEntityType, 1, 10),
+			},
+			html:       "a<b>bc</b>",
+			markdownV2: "a*bc*",
+		},
+	}
+	for i, tt := range testTable {
+		t.Logf("test #%d", i)
+		assert.Equal(t, tt.html, telegram.RenderHTML(tt.text, tt.entities))
+		assert.Equal(t, tt.markdownV2,
+			telegram.RenderMarkdownV2(tt.text, tt.entities))
+	}
+
+	msg := telegram.Message{
+		Text:     "hi",
+		Entities: []telegram.MessageEntity{entity(telegram.BoldEntityType, 0, 2)},
+	}
+	assert.Equal(t, "<b>hi</b>", msg.HTML())
+	assert.Equal(t, "*hi*", msg.MarkdownV2())
+}
+
+func TestParseHTML(t *testing.T) {
+	text, entities, err := telegram.ParseHTML(
+		`<b>bold <em>italic</em></b>, <a href="http://a.b/?c=1&amp;d=2">link</a>, ` +
+			`<a href="tg://user?id=42">Bob</a> &lt;3 👋 <span class="tg-spoiler">x</span>` +
+			`<pre><code class="language-go">a &amp;&amp; b</code></pre>` +
+			`<code></code><del>s</del><ins>u</ins>`)
+	require.NoError(t, err)
+	assert.Equal(t, "bold italic, link, Bob <3 👋 xa && bsu", text)
+	assert.Equal(t, []telegram.MessageEntity{
+		entity(telegram.BoldEntityType, 0, 11),
+		entity(telegram.ItalicEntityType, 5, 6),
+		{Type: telegram.TextLinkEntityType, Offset: 13, Length: 4,
+			URL: "http://a.b/?c=1&d=2"},
+		{Type: telegram.TextMentionEntityType, Offset: 19, Length: 3,
+			User: &telegram.User{ID: 42}},
+		entity(telegram.SpoilerEntityType, 29, 1),
+		{Type: telegram.PreEntityType, Offset: 30, Length: 6,
+			Language: "go"},
+		entity(telegram.StrikeEntityType, 36, 1),
+		entity(telegram.UnderlineEntityType, 37, 1),
+	}, entities)
+
+	// rendered text is parsed back
+	html := telegram.RenderHTML(text, entities)
+	text2, entities2, err := telegram.ParseHTML(html)
+	require.NoError(t, err)
+	assert.Equal(t, text, text2)
+	assert.Equal(t, entities, entities2)
+
+	for _, bad := range []string{
+		"<div>a</div>",
+		"<span>a</span>",
+		"<b>a",
+		"<b>a</i>",
+		"a</b>",
+		"a<br/>b",
+		`<a href="tg://user?id=bob">Bob</a>`,
+	} {
+		_, _, err := telegram.ParseHTML(bad)
+		assert.True(t, telegram.IsValidationError(err), "%s: %v", bad, err)
+	}
+}
+
+func TestCloneMessage_entities(t *testing.T) {
+	msg := &telegram.Message{
+		Chat:     telegram.Chat{ID: 1},
+		Text:     "hi",
+		Entities: []telegram.MessageEntity{entity(telegram.BoldEntityType, 0, 2)},
+	}
+	cfg, ok := telegram.CloneMessage(msg, nil).(*telegram.MessageCfg)
+	require.True(t, ok)
+	assert.Equal(t, msg.Entities, cfg.Entities)
+
+	// caption entities of photo are kept
+	msg = &telegram.Message{
+		Chat: telegram.Chat{ID: 1},
+		Photo: []telegram.PhotoSize{
+			{MetaFile: telegram.MetaFile{FileID: "small"}},
+			{MetaFile: telegram.MetaFile{FileID: "big"}},
+		},
+		Caption:         "cat",
+		CaptionEntities: []telegram.MessageEntity{entity(telegram.ItalicEntityType, 0, 3)},
+	}
+	photo, ok := telegram.CloneMessage(msg, nil).(*telegram.PhotoCfg)
+	require.True(t, ok)
+	assert.Equal(t, "big", photo.FileID)
+	assert.Equal(t, "cat", photo.Caption)
+	assert.Equal(t, msg.CaptionEntities, photo.CaptionEntities)
+	v, err := photo.Values()
+	require.NoError(t, err)
+	assert.Equal(t, `[{"type":"italic","offset":0,"length":3}]`,
+		v.Get("caption_entities"))
+}
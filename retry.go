@@ -0,0 +1,173 @@
+package telegram
+
+import (
+	"math/rand"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RetryCfg defines a retry policy for GetUpdatesWithRetry.
+// Delay before n-th retry in a row is
+// InitialInterval * Multiplier^(n-1), capped by MaxInterval
+// and randomized by RandomizationFactor.
+type RetryCfg struct {
+	// InitialInterval is a delay before the first retry.
+	// Optional, with default value as 1 second.
+	InitialInterval time.Duration
+	// MaxInterval caps a delay between retries.
+	// Optional, with default value as 1 minute.
+	MaxInterval time.Duration
+	// Multiplier increases a delay after each failed retry.
+	// Optional, with default value as 2.
+	Multiplier float64
+	// RandomizationFactor adds jitter to each delay,
+	// so delay is a random value in
+	// [delay - factor * delay, delay + factor * delay].
+	// Optional, DefaultRetryConfig uses 0.5,
+	// zero value disables jitter.
+	RandomizationFactor float64
+	// MaxElapsedTime stops retries if requests have been failing
+	// for longer than this time. The last error is returned then.
+	// Optional, with default value as 0 (retry forever).
+	MaxElapsedTime time.Duration
+	// IsFatal classifies errors that shouldn't be retried.
+	// Optional, with default value as IsFatalError.
+	IsFatal func(err error) bool
+	// OnRetry is invoked before waiting for the next retry.
+	// attempt starts from 1 and is reset after a successful request.
+	// Optional.
+	OnRetry func(err error, attempt int, delay time.Duration)
+}
+
+// DefaultRetryConfig is the default retry policy.
+var DefaultRetryConfig = RetryCfg{
+	InitialInterval:     time.Second,
+	MaxInterval:         time.Minute,
+	Multiplier:          2,
+	RandomizationFactor: 0.5,
+}
+
+// IsFatalError returns true if request with err can't succeed
+// after retry: bot token is invalid, bot is forbidden,
+// another getUpdates request or webhook is active (409 Conflict),
+// request config is invalid or context is done.
+func IsFatalError(err error) bool {
+	switch {
+	case err == context.Canceled, err == context.DeadlineExceeded:
+		return true
+	case IsUnauthorizedError(err), IsForbiddenError(err):
+		return true
+	case IsRequiredError(err), IsValidationError(err):
+		return true
+	}
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr.ErrorCode == 409
+	}
+	return false
+}
+
+// GetUpdatesWithRetry is like GetUpdates, but retries failed requests
+// according to retry policy. It returns error if error is fatal,
+// MaxElapsedTime is exceeded or ctx is done.
+func GetUpdatesWithRetry(
+	ctx context.Context,
+	api *API,
+	cfg UpdateCfg,
+	retry RetryCfg,
+	out chan<- Update) error {
+
+	defer close(out)
+	return getUpdates(ctx, api, cfg, newBackoff(retry), sendUpdate(ctx, out))
+}
+
+// GetUpdatesFunc is like GetUpdatesWithRetry, but invokes fn
+// for every update instead of sending it to a channel.
+// The next getUpdates request, that confirms received updates,
+// is made only after fn returns for all of them,
+// so a crash during fn doesn't lose an update.
+// It stops and returns error if fn returns error.
+func GetUpdatesFunc(
+	ctx context.Context,
+	api *API,
+	cfg UpdateCfg,
+	retry RetryCfg,
+	fn func(Update) error) error {
+
+	return getUpdates(ctx, api, cfg, newBackoff(retry), fn)
+}
+
+// ============== Internal ================================================== //
+
+type backoff struct {
+	cfg     RetryCfg
+	attempt int
+	started time.Time
+}
+
+func newBackoff(cfg RetryCfg) *backoff {
+	// Defaults
+	if cfg.InitialInterval <= 0 {
+		cfg.InitialInterval = DefaultRetryConfig.InitialInterval
+	}
+	if cfg.MaxInterval <= 0 {
+		cfg.MaxInterval = DefaultRetryConfig.MaxInterval
+	}
+	if cfg.Multiplier < 1 {
+		cfg.Multiplier = DefaultRetryConfig.Multiplier
+	}
+	if cfg.IsFatal == nil {
+		cfg.IsFatal = IsFatalError
+	}
+	return &backoff{cfg: cfg}
+}
+
+// next returns delay before the next retry
+// or false if err shouldn't be retried.
+// Delay is at least retry_after seconds returned by flood control.
+func (b *backoff) next(err error) (time.Duration, bool) {
+	if b.cfg.IsFatal(err) {
+		return 0, false
+	}
+	now := time.Now()
+	if b.attempt == 0 {
+		b.started = now
+	}
+	if b.cfg.MaxElapsedTime > 0 &&
+		now.Sub(b.started) >= b.cfg.MaxElapsedTime {
+		return 0, false
+	}
+	b.attempt++
+
+	delay := float64(b.cfg.InitialInterval)
+	for i := 1; i < b.attempt && delay < float64(b.cfg.MaxInterval); i++ {
+		delay *= b.cfg.Multiplier
+	}
+	if delay > float64(b.cfg.MaxInterval) {
+		delay = float64(b.cfg.MaxInterval)
+	}
+	if f := b.cfg.RandomizationFactor; f > 0 {
+		delay += delay * f * (2*rand.Float64() - 1)
+	}
+	if wait := float64(retryAfter(err)); delay < wait {
+		delay = wait
+	}
+	if b.cfg.OnRetry != nil {
+		b.cfg.OnRetry(err, b.attempt, time.Duration(delay))
+	}
+	return time.Duration(delay), true
+}
+
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+// retryAfter returns time to wait before the next request
+// if err is 429 Too Many Requests.
+func retryAfter(err error) time.Duration {
+	apiErr, ok := err.(*APIError)
+	if !ok || apiErr.Parameters == nil {
+		return 0
+	}
+	return time.Duration(apiErr.Parameters.RetryAfter) * time.Second
+}
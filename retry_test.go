@@ -0,0 +1,274 @@
+package telegram_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/m0sth8/httpmock"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+// sequenceResponder returns responders one by one,
+// the last one is repeated.
+func sequenceResponder(responders ...httpmock.Responder) httpmock.Responder {
+	i := 0
+	return func(req *http.Request) (*http.Response, error) {
+		r := responders[i]
+		if i < len(responders)-1 {
+			i++
+		}
+		return r(req)
+	}
+}
+
+var (
+	internalErrorResponder = httpmock.NewStringResponder(
+		http.StatusInternalServerError, "bad gateway")
+	unauthorizedResponder = httpmock.NewStringResponder(
+		http.StatusUnauthorized,
+		`{"ok":false,"error_code":401,"description":"Unauthorized"}`)
+	conflictResponder = httpmock.NewStringResponder(
+		http.StatusConflict,
+		`{"ok":false,"error_code":409,"description":"Conflict"}`)
+)
+
+func TestGetUpdatesWithRetry(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/getUpdates",
+		sequenceResponder(
+			internalErrorResponder,
+			internalErrorResponder,
+			httpmock.NewStringResponder(200, `
+			{"ok":true,"result":[{"update_id":10}]}`),
+			internalErrorResponder,
+			unauthorizedResponder,
+		),
+	)
+
+	type retry struct {
+		attempt int
+		delay   time.Duration
+	}
+	var retries []retry
+	cfg := telegram.RetryCfg{
+		InitialInterval: time.Millisecond,
+		Multiplier:      3,
+		OnRetry: func(err error, attempt int, delay time.Duration) {
+			assert.Error(t, err)
+			retries = append(retries, retry{attempt, delay})
+		},
+	}
+	out := make(chan telegram.Update, 10)
+	err := telegram.GetUpdatesWithRetry(
+		ctx, api, telegram.UpdateCfg{}, cfg, out)
+	assert.True(t, telegram.IsUnauthorizedError(err))
+
+	// delays grow and are reset after a successful request
+	assert.Equal(t, []retry{
+		{1, time.Millisecond},
+		{2, time.Millisecond * 3},
+		{1, time.Millisecond},
+	}, retries)
+
+	var updates []telegram.Update
+	for u := range out {
+		updates = append(updates, u)
+	}
+	assert.Equal(t, []telegram.Update{{UpdateID: 10}}, updates)
+}
+
+func TestGetUpdatesWithRetry_maxElapsedTime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/getUpdates",
+		internalErrorResponder,
+	)
+
+	attempts := 0
+	cfg := telegram.RetryCfg{
+		InitialInterval: time.Millisecond * 5,
+		MaxInterval:     time.Millisecond * 5,
+		MaxElapsedTime:  time.Millisecond * 20,
+		OnRetry: func(err error, attempt int, delay time.Duration) {
+			attempts = attempt
+			assert.Equal(t, time.Millisecond*5, delay)
+		},
+	}
+	err := telegram.GetUpdatesWithRetry(
+		ctx, api, telegram.UpdateCfg{}, cfg, make(chan telegram.Update))
+	require.Error(t, err)
+	assert.False(t, telegram.IsFatalError(err))
+	assert.NoError(t, ctx.Err())
+	assert.True(t, attempts > 1 && attempts <= 4, "attempts: %d", attempts)
+}
+
+func TestGetUpdatesWithRetry_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/getUpdates",
+		internalErrorResponder,
+	)
+	cfg := telegram.RetryCfg{
+		InitialInterval: time.Hour,
+		OnRetry: func(error, int, time.Duration) {
+			cancel()
+		},
+	}
+	err := telegram.GetUpdatesWithRetry(
+		ctx, api, telegram.UpdateCfg{}, cfg, make(chan telegram.Update))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestGetUpdatesWithRetry_retryAfter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/getUpdates",
+		httpmock.NewStringResponder(http.StatusTooManyRequests, `{
+			"ok":false,"error_code":429,
+			"description":"Too Many Requests: retry after 5",
+			"parameters":{"retry_after":5}}`),
+	)
+	var delay time.Duration
+	cfg := telegram.RetryCfg{
+		InitialInterval: time.Millisecond,
+		OnRetry: func(err error, attempt int, d time.Duration) {
+			apiErr, ok := err.(*telegram.APIError)
+			if assert.True(t, ok) && assert.NotNil(t, apiErr.Parameters) {
+				assert.Equal(t, 5, apiErr.Parameters.RetryAfter)
+			}
+			delay = d
+			cancel()
+		},
+	}
+	err := telegram.GetUpdatesWithRetry(
+		ctx, api, telegram.UpdateCfg{}, cfg, make(chan telegram.Update))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, time.Second*5, delay)
+}
+
+func TestGetUpdates(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/getUpdates",
+		internalErrorResponder,
+	)
+	// GetUpdates doesn't retry
+	out := make(chan telegram.Update)
+	err := telegram.GetUpdates(ctx, api, telegram.UpdateCfg{}, out)
+	require.Error(t, err)
+	_, ok := <-out
+	assert.False(t, ok, "out channel should be closed")
+}
+
+func TestIsFatalError(t *testing.T) {
+	testTable := []struct {
+		err   error
+		fatal bool
+	}{
+		{context.Canceled, true},
+		{context.DeadlineExceeded, true},
+		{telegram.NewRequiredError("url"), true},
+		{telegram.NewValidationError("url", "invalid"), true},
+		{&telegram.APIError{ErrorCode: 409}, true},
+		{&telegram.APIError{ErrorCode: 429}, false},
+		{&telegram.APIError{ErrorCode: 502}, false},
+		{fmt.Errorf("network error"), false},
+	}
+	for i, tt := range testTable {
+		assert.Equal(t, tt.fatal, telegram.IsFatalError(tt.err),
+			"test #%d: %v", i, tt.err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	for _, resp := range []httpmock.Responder{
+		forbiddenResponder,
+		unauthorizedResponder,
+		conflictResponder,
+	} {
+		httpmock.RegisterResponder(
+			"POST",
+			"https://api.telegram.org/bottoken/getMe",
+			resp,
+		)
+		_, err := api.GetMe(ctx)
+		assert.True(t, telegram.IsFatalError(err), "%v", err)
+	}
+}
+
+func TestGetUpdatesFunc(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	var offsets []string
+	next := sequenceResponder(
+		httpmock.NewStringResponder(200, `
+		{"ok":true,"result":[{"update_id":10},{"update_id":11}]}`),
+		httpmock.NewStringResponder(200, `
+		{"ok":true,"result":[{"update_id":12}]}`),
+	)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/getUpdates",
+		func(req *http.Request) (*http.Response, error) {
+			require.NoError(t, req.ParseForm())
+			offsets = append(offsets, req.Form.Get("offset"))
+			return next(req)
+		},
+	)
+
+	fnErr := fmt.Errorf("fn error")
+	var ids []int64
+	err := telegram.GetUpdatesFunc(ctx, api, telegram.UpdateCfg{Offset: 10},
+		telegram.RetryCfg{}, func(u telegram.Update) error {
+			ids = append(ids, u.UpdateID)
+			if len(ids) == 3 {
+				return fnErr
+			}
+			return nil
+		})
+	assert.Equal(t, fnErr, err)
+	assert.Equal(t, []int64{10, 11, 12}, ids)
+	assert.Equal(t, []string{"10", "12"}, offsets)
+}
@@ -0,0 +1,279 @@
+package telegram
+
+import (
+	"unicode/utf16"
+)
+
+// MaxMessageLength is a maximum length of message text
+// in UTF-16 code units after entities parsing.
+const MaxMessageLength = 4096
+
+// SplitMessage splits message into messages with text
+// not longer than limit UTF-16 code units.
+// Text is split on paragraph, line or word boundaries if possible.
+// It never cuts inside an entity or a markup construct
+// unless the entity itself is longer than limit:
+// text with entities (HTMLMode or Entities) is split by entities,
+// an entity longer than limit is split into several ones;
+// for MarkdownMode and MarkdownV2Mode text is split between
+// markup constructs and its length is counted with markup.
+// ReplyMarkup is kept only on the last message
+// and ReplyToMessageID only on the first one.
+//
+// ValidationError is returned if limit is less than 1,
+// HTML text can't be parsed or text longer than limit has only spaces.
+func SplitMessage(cfg MessageCfg, limit int) ([]MessageCfg, error) {
+	if limit < 1 {
+		return nil, NewValidationError("limit", "limit should be positive")
+	}
+	var (
+		units    []uint16
+		entities = cfg.Entities
+		unsafe   []bool
+	)
+	switch cfg.ParseMode {
+	case HTMLMode:
+		text, parsed, err := ParseHTML(cfg.Text)
+		if err != nil {
+			return nil, err
+		}
+		units, entities = utf16.Encode([]rune(text)), parsed
+	case MarkdownMode, MarkdownV2Mode:
+		units = utf16.Encode([]rune(cfg.Text))
+		unsafe = markdownUnsafe(units, cfg.ParseMode == MarkdownV2Mode)
+	default:
+		units = utf16.Encode([]rune(cfg.Text))
+	}
+	if len(units) <= limit {
+		return []MessageCfg{cfg}, nil
+	}
+	if unsafe == nil {
+		unsafe = entitiesUnsafe(units, entities)
+	}
+
+	chunks := splitUnits(units, limit, unsafe)
+	if len(chunks) == 0 {
+		// telegram doesn't send empty messages
+		return nil, NewValidationError("Text", "text has only spaces")
+	}
+	messages := make([]MessageCfg, len(chunks))
+	for i, chunk := range chunks {
+		msg := cfg
+		if i > 0 {
+			msg.ReplyToMessageID = 0
+		}
+		if i < len(chunks)-1 {
+			msg.ReplyMarkup = nil
+		}
+		text := string(utf16.Decode(units[chunk[0]:chunk[1]]))
+		chunkEntities := cutEntities(entities, chunk[0], chunk[1])
+		switch cfg.ParseMode {
+		case HTMLMode:
+			msg.Text = RenderHTML(text, chunkEntities)
+		case MarkdownMode, MarkdownV2Mode:
+			msg.Text = text
+		default:
+			msg.Text, msg.Entities = text, chunkEntities
+		}
+		messages[i] = msg
+	}
+	return messages, nil
+}
+
+// ============== Internal ================================================== //
+
+// splitUnits returns [start, end) ranges of chunks.
+// unsafe[pos] is true if text can't be cut before pos.
+// Spaces and new lines between chunks are dropped.
+func splitUnits(units []uint16, limit int, unsafe []bool) [][2]int {
+	var chunks [][2]int
+	start := 0
+	for {
+		start = skipSpaces(units, start)
+		if start >= len(units) {
+			return chunks
+		}
+		end := len(units)
+		if end-start > limit {
+			end = findCut(units, start, start+limit, unsafe)
+		}
+		trimmed := end
+		for trimmed > start && (units[trimmed-1] == ' ' || units[trimmed-1] == '\n') {
+			trimmed--
+		}
+		chunks = append(chunks, [2]int{start, trimmed})
+		start = end
+	}
+}
+
+// findCut finds a position to cut text in (start, max].
+// Paragraph boundaries are preferred over lines, lines over words.
+// A construct longer than limit is cut on a boundary too if possible.
+func findCut(units []uint16, start, max int, unsafe []bool) int {
+	if pos := findSeparator(units, start, max, unsafe); pos > 0 {
+		return pos
+	}
+	for pos := max; pos > start; pos-- {
+		if !unsafe[pos] && !isLowSurrogate(units[pos]) {
+			return pos
+		}
+	}
+	// a construct is longer than limit, cut it
+	if pos := findSeparator(units, start, max, nil); pos > 0 {
+		return pos
+	}
+	if isLowSurrogate(units[max]) && max-1 > start {
+		return max - 1
+	}
+	return max
+}
+
+// findSeparator returns the last safe position of the best separator
+// in (start, max] or 0. All positions are safe if unsafe is nil.
+func findSeparator(units []uint16, start, max int, unsafe []bool) int {
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		for pos := max; pos > start; pos-- {
+			if (unsafe == nil || !unsafe[pos]) && hasSeparator(units, pos, sep) {
+				return pos
+			}
+		}
+	}
+	return 0
+}
+
+// isLowSurrogate returns true for the second unit of a surrogate pair,
+// text can't be cut before it.
+func isLowSurrogate(u uint16) bool {
+	return u >= 0xdc00 && u < 0xe000
+}
+
+func hasSeparator(units []uint16, pos int, sep string) bool {
+	if pos+len(sep) > len(units) {
+		return false
+	}
+	for i := 0; i < len(sep); i++ {
+		if units[pos+i] != uint16(sep[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func skipSpaces(units []uint16, pos int) int {
+	for pos < len(units) && (units[pos] == ' ' || units[pos] == '\n') {
+		pos++
+	}
+	return pos
+}
+
+// entitiesUnsafe marks positions inside entities.
+func entitiesUnsafe(units []uint16, entities []MessageEntity) []bool {
+	unsafe := make([]bool, len(units)+1)
+	for _, e := range entities {
+		for pos := e.Offset + 1; pos < e.Offset+e.Length && pos < len(units); pos++ {
+			if pos > 0 {
+				unsafe[pos] = true
+			}
+		}
+	}
+	return unsafe
+}
+
+// cutEntities returns entities in [start, end) range
+// with offsets relative to start.
+func cutEntities(entities []MessageEntity, start, end int) []MessageEntity {
+	var result []MessageEntity
+	for _, e := range entities {
+		from, to := e.Offset, e.Offset+e.Length
+		if from < start {
+			from = start
+		}
+		if to > end {
+			to = end
+		}
+		if from >= to {
+			continue
+		}
+		e.Offset, e.Length = from-start, to-from
+		result = append(result, e)
+	}
+	return result
+}
+
+// markdownUnsafe marks positions inside markdown constructs:
+// bold, italic, code, pre, links and for MarkdownV2 also
+// underline, strikethrough and spoiler. Escaped characters
+// aren't constructs and can't be separated from a backslash.
+func markdownUnsafe(units []uint16, v2 bool) []bool {
+	const (
+		noLink = iota
+		linkText
+		linkTextEnd
+		linkURL
+	)
+	var (
+		unsafe = make([]bool, len(units)+1)
+		open   = map[string]bool{}
+		code   string
+		link   int
+	)
+	has := func(pos int, s string) bool {
+		return hasSeparator(units, pos, s)
+	}
+	for pos := 0; pos < len(units); {
+		if code != "" || link != noLink || len(open) > 0 {
+			unsafe[pos] = true
+		}
+		c := units[pos]
+		step := 1
+		switch {
+		case c == '\\' && (v2 || code == ""):
+			// escaped character, legacy markdown has no escapes in code
+			step = 2
+		case code != "":
+			if has(pos, code) {
+				step, code = len(code), ""
+			}
+		case link == linkURL:
+			if c == ')' {
+				link = noLink
+			}
+		case link == linkTextEnd && c != '(':
+			// [text] without url isn't a link
+			link = noLink
+			continue
+		case link == linkTextEnd:
+			link = linkURL
+		case has(pos, "```"):
+			step, code = 3, "```"
+		case c == '`':
+			code = "`"
+		case c == '[' && link == noLink:
+			link = linkText
+		case c == ']' && link == linkText:
+			link = linkTextEnd
+		default:
+			marker := string(rune(c))
+			switch {
+			case v2 && has(pos, "__"), v2 && has(pos, "||"):
+				marker, step = marker+marker, 2
+			case c == '*', c == '_', v2 && c == '~':
+			default:
+				marker = ""
+			}
+			if marker != "" {
+				if open[marker] {
+					delete(open, marker)
+				} else {
+					open[marker] = true
+				}
+			}
+		}
+		for i := 1; i < step && pos+i < len(units); i++ {
+			unsafe[pos+i] = true
+		}
+		pos += step
+	}
+	unsafe[len(units)] = false
+	return unsafe
+}
@@ -0,0 +1,215 @@
+package telegram_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/m0sth8/httpmock"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestSplitMessage(t *testing.T) {
+	testTable := []struct {
+		text      string
+		parseMode string
+		entities  []telegram.MessageEntity
+		limit     int
+
+		exp         []string
+		expEntities [][]telegram.MessageEntity
+		expErr      bool
+	}{
+		{
+			text:  "short",
+			limit: 10,
+			exp:   []string{"short"},
+		},
+		// paragraphs are preferred over lines and words
+		{
+			text:  "first line\nsecond\n\nthird line",
+			limit: 20,
+			exp:   []string{"first line\nsecond", "third line"},
+		},
+		{
+			text:  "one two three four",
+			limit: 9,
+			exp:   []string{"one two", "three", "four"},
+		},
+		// long word is cut, emoji isn't broken
+		{
+			text:  "abcdef👋gh",
+			limit: 7,
+			exp:   []string{"abcdef", "👋gh"},
+		},
+		// entity isn't cut
+		{
+			text:  "aa bb cc",
+			limit: 6,
+			entities: []telegram.MessageEntity{
+				entity(telegram.BoldEntityType, 0, 5),
+				entity(telegram.ItalicEntityType, 6, 2),
+			},
+			exp: []string{"aa bb", "cc"},
+			expEntities: [][]telegram.MessageEntity{
+				{entity(telegram.BoldEntityType, 0, 5)},
+				{entity(telegram.ItalicEntityType, 0, 2)},
+			},
+		},
+		// entity longer than limit is split
+		{
+			text:  "x aaaa bbbb",
+			limit: 5,
+			entities: []telegram.MessageEntity{
+				entity(telegram.BoldEntityType, 2, 9),
+			},
+			exp: []string{"x", "aaaa", "bbbb"},
+			expEntities: [][]telegram.MessageEntity{
+				nil,
+				{entity(telegram.BoldEntityType, 0, 4)},
+				{entity(telegram.BoldEntityType, 0, 4)},
+			},
+		},
+		// html tags are closed in every part
+		{
+			text:      "<b>aa bb</b> <i>cc dd</i>",
+			parseMode: telegram.HTMLMode,
+			limit:     6,
+			exp:       []string{"<b>aa bb</b>", "<i>cc dd</i>"},
+		},
+		{
+			text:      "<b>aaaa bbbb</b>",
+			parseMode: telegram.HTMLMode,
+			limit:     6,
+			exp:       []string{"<b>aaaa</b>", "<b>bbbb</b>"},
+		},
+		// markdown constructs aren't cut
+		{
+			text:      "*a b* [c d](http://e.f/g_h) ```\nx y\n``` z\\_w",
+			parseMode: telegram.MarkdownV2Mode,
+			limit:     22,
+			exp: []string{
+				"*a b*",
+				"[c d](http://e.f/g_h)",
+				"```\nx y\n``` z\\_w",
+			},
+		},
+		{
+			text:      "__a b__ ||c d|| e",
+			parseMode: telegram.MarkdownV2Mode,
+			limit:     8,
+			exp:       []string{"__a b__", "||c d||", "e"},
+		},
+		{
+			text:      "_a b_ `c\\` d",
+			parseMode: telegram.MarkdownMode,
+			limit:     5,
+			exp:       []string{"_a b_", "`c\\`", "d"},
+		},
+		// text can't be split by non positive limit
+		{
+			text:   "hello world",
+			limit:  0,
+			expErr: true,
+		},
+		{
+			text:   "hello world",
+			limit:  -1,
+			expErr: true,
+		},
+	}
+	for i, tt := range testTable {
+		t.Logf("test #%d", i)
+		cfg := telegram.NewMessage(1, tt.text)
+		cfg.ParseMode = tt.parseMode
+		cfg.Entities = tt.entities
+		parts, err := telegram.SplitMessage(cfg, tt.limit)
+		if tt.expErr {
+			assert.True(t, telegram.IsValidationError(err))
+			continue
+		}
+		require.NoError(t, err)
+		var texts []string
+		var entities [][]telegram.MessageEntity
+		for _, part := range parts {
+			texts = append(texts, part.Text)
+			entities = append(entities, part.Entities)
+			assert.Equal(t, tt.parseMode, part.ParseMode)
+		}
+		assert.Equal(t, tt.exp, texts)
+		if tt.expEntities != nil {
+			assert.Equal(t, tt.expEntities, entities)
+		}
+	}
+
+	cfg := telegram.NewMessage(1, "<b>a")
+	cfg.ParseMode = telegram.HTMLMode
+	_, err := telegram.SplitMessage(cfg, 10)
+	assert.True(t, telegram.IsValidationError(err))
+
+	// long text with only spaces can't be sent
+	_, err = telegram.SplitMessage(telegram.NewMessage(1, strings.Repeat(" \n", 10)), 10)
+	assert.True(t, telegram.IsValidationError(err))
+	parts, err := telegram.SplitMessage(telegram.NewMessage(1, "   "), 10)
+	require.NoError(t, err)
+	assert.Len(t, parts, 1)
+}
+
+func TestAPI_SendLongMessage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	api := telegram.New(apiToken)
+
+	var requests []http.Request
+	failAt := 0
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/sendMessage",
+		func(req *http.Request) (*http.Response, error) {
+			require.NoError(t, req.ParseForm())
+			requests = append(requests, *req)
+			if len(requests) == failAt {
+				return internalErrorResponder(req)
+			}
+			return httpmock.NewStringResponder(200, fmt.Sprintf(
+				`{"ok":true,"result":{"message_id":%d,"text":%q}}`,
+				len(requests), req.PostForm.Get("text")))(req)
+		},
+	)
+
+	paragraph := strings.Repeat("word ", 700)
+	cfg := telegram.NewMessage(1, paragraph+"\n\n"+paragraph+"\n\n"+paragraph)
+	cfg.ReplyToMessageID = 10
+	cfg.ReplyMarkup = telegram.ForceReply{ForceReply: true}
+
+	messages, err := api.SendLongMessage(ctx, cfg)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	require.Len(t, requests, 3)
+	for i, msg := range messages {
+		assert.Equal(t, int64(i+1), msg.MessageID)
+		assert.Equal(t, strings.TrimSpace(paragraph), msg.Text)
+	}
+	assert.Equal(t, "10", requests[0].PostForm.Get("reply_to_message_id"))
+	assert.Equal(t, "", requests[0].PostForm.Get("reply_markup"))
+	assert.Equal(t, "", requests[1].PostForm.Get("reply_to_message_id"))
+	assert.Equal(t, "", requests[1].PostForm.Get("reply_markup"))
+	assert.Equal(t, "", requests[2].PostForm.Get("reply_to_message_id"))
+	assert.Equal(t, `{"force_reply":true,"selective":false}`,
+		requests[2].PostForm.Get("reply_markup"))
+
+	// sent messages are returned with error
+	requests, failAt = nil, 2
+	messages, err = api.SendLongMessage(ctx, cfg)
+	assert.Error(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, int64(1), messages[0].MessageID)
+	assert.Len(t, requests, 2)
+}
@@ -0,0 +1,121 @@
+package telebot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+type callbackAnswerKey struct{}
+
+// AnswerConfig helps to configure AutoAnswer middleware.
+type AnswerConfig struct {
+	// Timeout after which callback query is answered
+	// if handler is still running, it's measured by AfterFunc.
+	// Zero timeout disables it.
+	Timeout time.Duration
+	// ErrorText is shown to user if handler returns an error.
+	// Nothing is shown if it's empty.
+	ErrorText string
+	// ErrorAlert shows ErrorText as an alert instead of a notification.
+	ErrorAlert bool
+}
+
+// AutoAnswer returns a middleware that answers callback query
+// if handler hasn't answered it by AnswerCallback,
+// so client stops showing progress on the pressed button.
+// Query is answered when handler returns or after a second.
+func AutoAnswer() MiddlewareFunc {
+	return AutoAnswerWithConfig(AnswerConfig{
+		Timeout: time.Second,
+	})
+}
+
+// AutoAnswerWithConfig takes AnswerConfig and returns
+// AutoAnswer middleware. Answer error is returned
+// if handler returns no error.
+func AutoAnswerWithConfig(cfg AnswerConfig) MiddlewareFunc {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			query := GetUpdate(ctx).CallbackQuery
+			if query == nil {
+				return next.Handle(ctx)
+			}
+			a := &callbackAnswer{}
+			ctx = context.WithValue(ctx, callbackAnswerKey{}, a)
+			if cfg.Timeout > 0 {
+				stop := AfterFunc(ctx, cfg.Timeout, func() {
+					a.answerByTimeout(ctx, telegram.NewAnswerCallback(query.ID, ""))
+				})
+				defer stop()
+			}
+
+			err := next.Handle(ctx)
+			answer := telegram.NewAnswerCallback(query.ID, "")
+			if err != nil {
+				answer.Text = cfg.ErrorText
+				answer.ShowAlert = cfg.ErrorAlert && cfg.ErrorText != ""
+			}
+			aErr := a.answer(ctx, answer)
+			if err != nil {
+				return err
+			}
+			return aErr
+		})
+	}
+}
+
+// AnswerCallback answers callback query of the current update.
+// CallbackQueryID is taken from update if it's empty.
+// Under AutoAnswer middleware query is answered only once,
+// answer is skipped if query is already answered.
+func AnswerCallback(ctx context.Context, cfg telegram.AnswerCallbackCfg) error {
+	if cfg.CallbackQueryID == "" {
+		if query := GetUpdate(ctx).CallbackQuery; query != nil {
+			cfg.CallbackQueryID = query.ID
+		}
+	}
+	if a, ok := ctx.Value(callbackAnswerKey{}).(*callbackAnswer); ok {
+		return a.answer(ctx, cfg)
+	}
+	_, err := GetAPI(ctx).AnswerCallbackQuery(ctx, cfg)
+	return err
+}
+
+// ============== Internal ================================================== //
+
+type callbackAnswer struct {
+	mu       sync.Mutex
+	answered bool
+	// err is an error of an answer sent by timeout
+	err error
+}
+
+// answer sends the first answer and returns its error,
+// later calls return error of an answer sent by timeout once.
+// Lock isn't held while answer is sent.
+func (a *callbackAnswer) answer(ctx context.Context, cfg telegram.AnswerCallbackCfg) error {
+	a.mu.Lock()
+	if a.answered {
+		err := a.err
+		a.err = nil
+		a.mu.Unlock()
+		return err
+	}
+	a.answered = true
+	a.mu.Unlock()
+	_, err := GetAPI(ctx).AnswerCallbackQuery(ctx, cfg)
+	return err
+}
+
+// answerByTimeout sends the first answer and keeps its error
+// for the next caller.
+func (a *callbackAnswer) answerByTimeout(ctx context.Context, cfg telegram.AnswerCallbackCfg) {
+	if err := a.answer(ctx, cfg); err != nil {
+		a.mu.Lock()
+		a.err = err
+		a.mu.Unlock()
+	}
+}
@@ -0,0 +1,101 @@
+package telebot_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/telebot/telebottest"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestAutoAnswer(t *testing.T) {
+	var errs []error
+	var tt *telebottest.Tester
+	tt = telebottest.New(t, func(api *telegram.API) *telebot.Bot {
+		b := telebot.NewWithAPI(api)
+		b.ErrorFunc(func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		})
+		b.Use(telebot.AutoAnswerWithConfig(telebot.AnswerConfig{
+			Timeout:    time.Second,
+			ErrorText:  "Something went wrong",
+			ErrorAlert: true,
+		}))
+		b.Use(telebot.Commands(map[string]telebot.Commander{
+			"start": telebot.CommandFunc(
+				func(ctx context.Context, arg string) error {
+					cfg := telegram.NewMessage(
+						telebot.GetUpdate(ctx).Message.Chat.ID, "Choose")
+					cfg.ReplyMarkup = telegram.InlineKeyboardMarkup{
+						InlineKeyboard: telegram.NewVInlineKeyboard(
+							"",
+							[]string{"Silent", "Answer", "Fail", "Slow", "Bad"},
+							[]string{"silent", "answer", "fail", "slow", "bad"}),
+					}
+					_, err := telebot.GetAPI(ctx).SendMessage(ctx, cfg)
+					return err
+				}),
+		}))
+		b.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+			"silent": telebot.CallbackFunc(
+				func(context.Context, string) error { return nil }),
+			"answer": telebot.CallbackFunc(
+				func(ctx context.Context, data string) error {
+					return telebot.AnswerCallback(ctx, telegram.AnswerCallbackCfg{
+						Text: "Done",
+					})
+				}),
+			"fail": telebot.CallbackFunc(
+				func(context.Context, string) error {
+					return fmt.Errorf("fail")
+				}),
+			"slow": telebot.CallbackFunc(
+				func(ctx context.Context, data string) error {
+					// query is answered by timeout
+					tt.Clock.Advance(time.Millisecond * 999)
+					assert.Len(t, tt.Server.Calls("answerCallbackQuery"), 3)
+					tt.Clock.Advance(time.Millisecond)
+					assert.Len(t, tt.Server.Calls("answerCallbackQuery"), 4)
+					return telebot.AnswerCallback(ctx,
+						telegram.NewAnswerCallback("", "Too late"))
+				}),
+			"bad": telebot.CallbackFunc(
+				func(ctx context.Context, data string) error {
+					// answer error is returned only to the handler
+					err := telebot.AnswerCallback(ctx,
+						telegram.NewAnswerCallback("bad", "Bad"))
+					assert.Error(t, err)
+					return nil
+				}),
+		}))
+		return b
+	})
+	defer tt.Close()
+
+	user := tt.User(42)
+	user.Sends("/start")
+	user.ExpectMessage(telebottest.Text("Choose"))
+
+	user.Presses("Silent")
+	user.ExpectAnswer("")
+	user.Presses("Answer")
+	user.ExpectAnswer("Done")
+	user.Presses("Fail")
+	user.ExpectAnswer("Something went wrong")
+	user.Presses("Slow")
+	user.ExpectAnswer("")
+	user.Presses("Bad")
+	tt.Run()
+
+	calls := tt.Server.Calls("answerCallbackQuery")
+	assert.Len(t, calls, 5)
+	assert.Equal(t, "true", calls[2].Params.Get("show_alert"))
+	if assert.Len(t, errs, 1) {
+		assert.EqualError(t, errs[0], "fail")
+	}
+	user.ExpectNoMessages()
+}
@@ -1,10 +1,8 @@
 package telebot
 
 import (
-	"encoding/json"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"time"
 
 	"github.com/bot-api/telegram"
 	"golang.org/x/net/context"
@@ -53,6 +51,8 @@ type Bot struct {
 	handler    Handler
 	middleware []MiddlewareFunc
 	errFunc    ErrorFunc
+	retry      telegram.RetryCfg
+	offsets    OffsetStore
 }
 
 // NewWithAPI returns bot with custom API client
@@ -63,6 +63,7 @@ func NewWithAPI(api *telegram.API) *Bot {
 		errFunc: func(ctx context.Context, err error) {
 			log.Printf("update error: %s", err.Error())
 		},
+		retry: defaultRetryConfig(),
 	}
 }
 
@@ -92,40 +93,63 @@ func (b *Bot) ErrorFunc(errFunc ErrorFunc) {
 	b.errFunc = errFunc
 }
 
+// Retry sets a retry policy for failed getUpdates requests.
+// By default telegram.DefaultRetryConfig is used
+// and every retry is printed to standard log.
+func (b *Bot) Retry(cfg telegram.RetryCfg) {
+	b.retry = cfg
+}
+
+// OffsetStore sets a store for update offset.
+// ServeWithConfig starts from stored offset and commits the next
+// offset after every received update is handled by the chain
+// (even if it returns an error), so updates are processed
+// at least once. Updates handled by webhook or HandleUpdate
+// aren't committed, use SkipProcessed middleware for them.
+func (b *Bot) OffsetStore(store OffsetStore) {
+	b.offsets = store
+}
+
 // ServeWithConfig runs update cycle with custom update config.
+// Failed getUpdates requests are retried according to retry policy,
+// see Retry. It returns error if error is fatal or retries are exhausted.
+// If offset store is set, stored offset is used unless cfg.Offset
+// is greater.
 func (b *Bot) ServeWithConfig(ctx context.Context, cfg telegram.UpdateCfg) error {
 	if err := b.updateMe(ctx); err != nil {
 		return err
 	}
+	if b.offsets != nil {
+		offset, err := b.offsets.Offset(ctx)
+		if err != nil {
+			return err
+		}
+		if offset > cfg.Offset {
+			cfg.Offset = offset
+		}
+	}
 
-	var rErr error
-	errCh := make(chan error, 1)
-	updatesCh := make(chan telegram.Update)
-	go func() {
-		errCh <- telegram.GetUpdates(
-			ctx,
-			b.api,
-			cfg,
-			updatesCh)
-	}()
-loop:
-	for {
-		select {
-		case rErr = <-errCh:
-			break loop
-		case update, ok := <-updatesCh:
-			if !ok {
-				// update channel was closed, wait for error
-				select {
-				case rErr = <-errCh:
-					break loop
-				}
+	return telegram.GetUpdatesFunc(
+		ctx,
+		b.api,
+		cfg,
+		b.retry,
+		func(update telegram.Update) error {
+			// the rest of updates is received again after restart
+			if err := ctx.Err(); err != nil {
+				return err
 			}
 			b.handleUpdate(ctx, &update)
+			b.commitOffset(ctx, &update)
+			return nil
+		})
+}
 
-		}
-	}
-	return rErr
+// HandleUpdate runs update through middleware chain and handler
+// synchronously. Errors are passed to ErrorFunc.
+// It helps to handle updates received in a custom way, e.x. in tests.
+func (b *Bot) HandleUpdate(ctx context.Context, update *telegram.Update) {
+	b.handleUpdate(ctx, update)
 }
 
 // Serve runs update cycle with default update config.
@@ -135,38 +159,17 @@ func (b *Bot) Serve(ctx context.Context) error {
 	return b.ServeWithConfig(ctx, cfg)
 }
 
-// ServeByWebhook returns webhook handler,
-// that can handle incoming telegram webhook messages.
-//
-// Use IsWebhook function to identify webhook updates.
-func (b *Bot) ServeByWebhook(ctx context.Context) (http.HandlerFunc, error) {
-	if err := b.updateMe(ctx); err != nil {
-		return nil, err
-	}
+// ============== Internal ================================================== //
 
-	updatesCh := make(chan telegram.Update)
-	go func() {
-	loop:
-		for {
-			select {
-			case <-ctx.Done():
-				break loop
-			case update := <-updatesCh:
-				b.handleUpdate(
-					context.WithValue(
-						ctx,
-						webhookKey{},
-						struct{}{}),
-					&update,
-				)
-			}
-		}
-	}()
-	return b.getWebhookHandler(ctx, updatesCh), nil
+func defaultRetryConfig() telegram.RetryCfg {
+	cfg := telegram.DefaultRetryConfig
+	cfg.OnRetry = func(err error, attempt int, delay time.Duration) {
+		log.Printf("getUpdates error: %s, retry #%d in %s",
+			err.Error(), attempt, delay)
+	}
+	return cfg
 }
 
-// ============== Internal ================================================== //
-
 func (b *Bot) updateMe(ctx context.Context) (err error) {
 	b.me, err = b.api.GetMe(ctx)
 	return err
@@ -191,27 +194,12 @@ func (b *Bot) handleUpdate(ctx context.Context, update *telegram.Update) {
 	}
 }
 
-func (b *Bot) getWebhookHandler(
-	ctx context.Context,
-	out chan<- telegram.Update) http.HandlerFunc {
-
-	return func(w http.ResponseWriter, r *http.Request) {
-		bytes, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-
-		var update telegram.Update
-		err = json.Unmarshal(bytes, &update)
-		if err != nil {
-			log.Println(err)
-			return
-		}
-		select {
-		case out <- update:
-		case <-ctx.Done():
-			return
-		}
+func (b *Bot) commitOffset(ctx context.Context, update *telegram.Update) {
+	if b.offsets == nil {
+		return
+	}
+	err := b.offsets.Commit(ctx, update.UpdateID+1)
+	if err != nil && b.errFunc != nil {
+		b.errFunc(WithUpdate(WithAPI(ctx, b.api), update), err)
 	}
 }
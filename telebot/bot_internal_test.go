@@ -1,11 +1,9 @@
 package telebot
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -108,61 +106,6 @@ func TestBot_handleUpdate(t *testing.T) {
 
 }
 
-func TestBot_getWebhookHandler(t *testing.T) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
-	defer cancel()
-
-	api := telegram.New("token")
-	b := NewWithAPI(api)
-	expUpd := telegram.Update{
-		UpdateID: 10,
-		Message: &telegram.Message{
-			Text: "message",
-		},
-	}
-	ch := make(chan telegram.Update, 1)
-	whHandler := b.getWebhookHandler(ctx, ch)
-
-	{
-		w := httptest.NewRecorder()
-		// prepare request
-		buf := &bytes.Buffer{}
-		err := json.NewEncoder(buf).Encode(expUpd)
-		require.NoError(t, err)
-		req, err := http.NewRequest("POST", "", buf)
-		require.NoError(t, err)
-
-		whHandler(w, req)
-
-		select {
-		case <-ctx.Done():
-			require.NoError(t, ctx.Err())
-		case upd := <-ch:
-			assert.EqualValues(t, expUpd, upd)
-		}
-	}
-
-	// TODO (m0sth8): what to do with errors during webhook handling??
-	// right now it's just logging
-	//{
-	//	w := httptest.NewRecorder()
-	//	// prepare request
-	//	req, err := http.NewRequest("POST", "",
-	//		bytes.NewBufferString("bad json"))
-	//	require.NoError(t, err)
-	//
-	//	whHandler(w, req)
-	//
-	//	select {
-	//	case <- ctx.Done():
-	//		require.NoError(t, ctx.Err())
-	//	case upd := <- ch:
-	//		assert.EqualValues(t, expUpd, upd)
-	//	}
-	//}
-
-}
-
 func NewAPIResponder(status int, result interface{}) httpmock.Responder {
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -291,100 +234,115 @@ func TestBot_Serve(t *testing.T) {
 	}
 }
 
-func TestBot_ServeByWebhook(t *testing.T) {
+func TestBot_Serve_retry(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
 	defer cancel()
-	httpmock.Activate()
-	defer httpmock.DeactivateAndReset()
 
-	api := telegram.New("_token")
-
-	expUpd := []telegram.Update{
-		{
-			UpdateID: 10,
-			Message: &telegram.Message{
-				Text: "message",
-			},
-		},
-		{
-			UpdateID: 11,
-			Message: &telegram.Message{
-				Text: "message",
-			},
-		},
-	}
-	expMe := telegram.User{
-		ID:       10,
-		Username: "test_bot",
-	}
+	client := &http.Client{}
+	api := telegram.NewWithClient("_token", client)
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
 
 	httpmock.RegisterResponder(
 		"POST",
 		"https://api.telegram.org/bot_token/getMe",
-		NewAPIResponder(200, expMe),
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+	failed := false
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getUpdates",
+		func(req *http.Request) (*http.Response, error) {
+			if !failed {
+				failed = true
+				return httpmock.NewStringResponse(502, "bad gateway"), nil
+			}
+			return NewAPIResponder(200, []telegram.Update{
+				{UpdateID: 10},
+			})(req)
+		},
 	)
 
 	b := NewWithAPI(api)
-
-	handleCh := make(chan context.Context, 1)
+	retries := 0
+	b.Retry(telegram.RetryCfg{
+		InitialInterval: time.Millisecond,
+		OnRetry: func(error, int, time.Duration) {
+			retries++
+		},
+	})
 	b.HandleFunc(func(ctx context.Context) error {
-		select {
-		case handleCh <- ctx:
-		case <-ctx.Done():
-		}
+		assert.Equal(t, int64(10), GetUpdate(ctx).UpdateID)
+		cancel()
 		return nil
 	})
+	err := b.Serve(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, retries)
+}
 
-	whHandler, err := b.ServeByWebhook(ctx)
-	require.NoError(t, err)
-
-	{
-		w := httptest.NewRecorder()
-		// prepare request
-		buf := &bytes.Buffer{}
-		err := json.NewEncoder(buf).Encode(expUpd[0])
-		require.NoError(t, err)
-		req, err := http.NewRequest("POST", "", buf)
-		require.NoError(t, err)
-
-		go whHandler(w, req)
+func TestBot_Serve_offsetStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
 
-	}
+	client := &http.Client{}
+	api := telegram.NewWithClient("_token", client)
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
 
-	// got first update
-	var update1 *telegram.Update
-	select {
-	case handleCtx1 := <-handleCh:
-		update1 = GetUpdate(handleCtx1)
-	case <-ctx.Done():
-		require.NoError(t, ctx.Err())
-	}
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+	var offsets []string
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getUpdates",
+		func(req *http.Request) (*http.Response, error) {
+			require.NoError(t, req.ParseForm())
+			offsets = append(offsets, req.Form.Get("offset"))
+			return NewAPIResponder(200, []telegram.Update{
+				{UpdateID: 10},
+				{UpdateID: 11},
+				{UpdateID: 12},
+			})(req)
+		},
+	)
 
-	assert.Equal(t, expMe, *b.me)
-	assert.Equal(t, expUpd[0], *update1)
+	store := NewMemoryOffsetStore()
+	require.NoError(t, store.Commit(ctx, 10))
 
-	{
-		w := httptest.NewRecorder()
-		// prepare request
-		buf := &bytes.Buffer{}
-		err := json.NewEncoder(buf).Encode(expUpd[1])
-		require.NoError(t, err)
-		req, err := http.NewRequest("POST", "", buf)
+	b := NewWithAPI(api)
+	b.OffsetStore(store)
+	b.HandleFunc(func(ctx context.Context) error {
+		offset, err := store.Offset(ctx)
 		require.NoError(t, err)
+		// offset is committed only after update is handled
+		assert.Equal(t, GetUpdate(ctx).UpdateID, offset)
+		if GetUpdate(ctx).UpdateID == 11 {
+			cancel()
+		}
+		return fmt.Errorf("handler error")
+	})
+	var errs []string
+	b.ErrorFunc(func(_ context.Context, err error) {
+		errs = append(errs, err.Error())
+	})
+	err := b.Serve(ctx)
+	assert.Equal(t, context.Canceled, err)
+	// getUpdates isn't requested again until all updates are handled
+	assert.Equal(t, []string{"10"}, offsets)
 
-		go whHandler(w, req)
-
-	}
-
-	// got second update
-	var update2 *telegram.Update
-	select {
-	case handleCtx1 := <-handleCh:
-		update2 = GetUpdate(handleCtx1)
-	case <-ctx.Done():
-		require.NoError(t, ctx.Err())
-	}
-
-	assert.Equal(t, expUpd[1], *update2)
+	// update 12 isn't handled after stop
+	offset, err := store.Offset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), offset)
+	assert.Equal(t, []string{"handler error", "handler error"}, errs)
 
+	// updates received in another way aren't committed
+	b.HandleUpdate(context.Background(), &telegram.Update{UpdateID: 12})
+	offset, err = store.Offset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), offset)
 }
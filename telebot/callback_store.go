@@ -0,0 +1,240 @@
+package telebot
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+type callbackStoreKey struct{}
+
+// ErrCallbackExpired is returned by CallbackStore
+// if callback data is expired or unknown.
+var ErrCallbackExpired = errors.New("callback data is expired")
+
+// IsCallbackExpired returns true if err is ErrCallbackExpired.
+func IsCallbackExpired(err error) bool {
+	return err == ErrCallbackExpired
+}
+
+// CallbackStore keeps callback data longer than
+// telegram.MaxCallbackDataLength under short generated keys.
+// Data is kept for ttl given to a store constructor,
+// buttons with expired data get ErrCallbackExpired.
+type CallbackStore interface {
+	telegram.CallbackDataStore
+	// GetCallbackData returns data saved under key
+	// or ErrCallbackExpired.
+	GetCallbackData(ctx context.Context, key string) (string, error)
+}
+
+// MemoryCallbackStore keeps callback data in memory.
+// Data is lost on restart.
+type MemoryCallbackStore struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	items callbackItems
+}
+
+// NewMemoryCallbackStore returns empty MemoryCallbackStore
+// that keeps data for ttl.
+func NewMemoryCallbackStore(ttl time.Duration) *MemoryCallbackStore {
+	return &MemoryCallbackStore{
+		ttl:   ttl,
+		items: callbackItems{},
+	}
+}
+
+// PutCallbackData saves data and returns a new key for it.
+// Expired data is removed.
+func (s *MemoryCallbackStore) PutCallbackData(ctx context.Context, data string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := Now(ctx)
+	s.items.prune(now)
+	return s.items.put(now, s.ttl, data)
+}
+
+// GetCallbackData returns data saved under key.
+func (s *MemoryCallbackStore) GetCallbackData(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.items.get(Now(ctx), key)
+}
+
+// FileCallbackStore keeps callback data in a json file.
+// File is replaced atomically on every put, BuildWithStore
+// puts all buttons of a keyboard at once.
+type FileCallbackStore struct {
+	mu     sync.Mutex
+	path   string
+	ttl    time.Duration
+	items  callbackItems
+	loaded bool
+}
+
+// NewFileCallbackStore returns FileCallbackStore that keeps data
+// in path for ttl. File is created on the first put.
+func NewFileCallbackStore(path string, ttl time.Duration) *FileCallbackStore {
+	return &FileCallbackStore{
+		path: path,
+		ttl:  ttl,
+	}
+}
+
+// PutCallbackData saves data to file and returns a new key for it.
+// Expired data is removed.
+func (s *FileCallbackStore) PutCallbackData(ctx context.Context, data string) (string, error) {
+	keys, err := s.PutCallbackDataBatch(ctx, []string{data})
+	if err != nil {
+		return "", err
+	}
+	return keys[0], nil
+}
+
+// PutCallbackDataBatch saves data to file by one write
+// and returns new keys for it. Expired data is removed.
+func (s *FileCallbackStore) PutCallbackDataBatch(ctx context.Context, data []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	now := Now(ctx)
+	s.items.prune(now)
+	keys := make([]string, 0, len(data))
+	var err error
+	for _, d := range data {
+		var key string
+		if key, err = s.items.put(now, s.ttl, d); err != nil {
+			break
+		}
+		keys = append(keys, key)
+	}
+	if err == nil {
+		err = s.write(s.items)
+	}
+	if err != nil {
+		// forget data that isn't saved
+		for _, key := range keys {
+			delete(s.items, key)
+		}
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetCallbackData returns data saved under key in file.
+func (s *FileCallbackStore) GetCallbackData(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	return s.items.get(Now(ctx), key)
+}
+
+// GetCallbackStore returns CallbackStore or nil for current context.
+func GetCallbackStore(ctx context.Context) CallbackStore {
+	if store, ok := ctx.Value(callbackStoreKey{}).(CallbackStore); ok {
+		return store
+	}
+	return nil
+}
+
+// WithCallbackStore returns a new context with CallbackStore inside.
+func WithCallbackStore(ctx context.Context, store CallbackStore) context.Context {
+	return context.WithValue(ctx, callbackStoreKey{}, store)
+}
+
+// StoreCallbacks returns a middleware that puts store to context.
+// Callbacks middleware resolves stored callback data with it,
+// handlers build keyboards with it:
+//
+//	markup, err := telegram.NewInlineKeyboardBuilder().
+//		Payload("Open", "menu", longPayload).
+//		BuildWithStore(ctx, telebot.GetCallbackStore(ctx))
+func StoreCallbacks(store CallbackStore) MiddlewareFunc {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			return next.Handle(WithCallbackStore(ctx, store))
+		})
+	}
+}
+
+// ============== Internal ================================================== //
+
+// callbackKeySize is a number of random bytes in a key,
+// it's encoded to 12 characters.
+const callbackKeySize = 9
+
+type callbackItem struct {
+	Data    string    `json:"data"`
+	Expires time.Time `json:"expires"`
+}
+
+type callbackItems map[string]callbackItem
+
+func (items callbackItems) put(now time.Time, ttl time.Duration, data string) (string, error) {
+	b := make([]byte, callbackKeySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	key := base64.RawURLEncoding.EncodeToString(b)
+	items[key] = callbackItem{
+		Data:    data,
+		Expires: now.Add(ttl),
+	}
+	return key, nil
+}
+
+func (items callbackItems) get(now time.Time, key string) (string, error) {
+	item, ok := items[key]
+	if !ok || !now.Before(item.Expires) {
+		return "", ErrCallbackExpired
+	}
+	return item.Data, nil
+}
+
+func (items callbackItems) prune(now time.Time) {
+	for key, item := range items {
+		if !now.Before(item.Expires) {
+			delete(items, key)
+		}
+	}
+}
+
+func (s *FileCallbackStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	items := callbackItems{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+	}
+	s.items = items
+	s.loaded = true
+	return nil
+}
+
+func (s *FileCallbackStore) write(items callbackItems) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data)
+}
@@ -0,0 +1,156 @@
+package telebot_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func testCallbackStore(t *testing.T, newStore func() telebot.CallbackStore) {
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := telebot.WithClock(context.Background(),
+		telebot.ClockFunc(func() time.Time { return now }))
+	store := newStore()
+
+	key1, err := store.PutCallbackData(ctx, "first")
+	require.NoError(t, err)
+	key2, err := store.PutCallbackData(ctx, "second")
+	require.NoError(t, err)
+	assert.NotEqual(t, key1, key2)
+	assert.True(t, len(telegram.StoredCallbackPrefix+key1) <= telegram.MaxCallbackDataLength)
+	assert.False(t, strings.Contains(key1, ":"))
+
+	data, err := store.GetCallbackData(ctx, key1)
+	require.NoError(t, err)
+	assert.Equal(t, "first", data)
+
+	_, err = store.GetCallbackData(ctx, "unknown")
+	assert.True(t, telebot.IsCallbackExpired(err))
+
+	now = now.Add(time.Minute)
+	key3, err := store.PutCallbackData(ctx, "third")
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	_, err = store.GetCallbackData(ctx, key2)
+	assert.True(t, telebot.IsCallbackExpired(err))
+	data, err = store.GetCallbackData(ctx, key3)
+	require.NoError(t, err)
+	assert.Equal(t, "third", data)
+}
+
+func TestMemoryCallbackStore(t *testing.T) {
+	testCallbackStore(t, func() telebot.CallbackStore {
+		return telebot.NewMemoryCallbackStore(time.Minute * 2)
+	})
+}
+
+func TestFileCallbackStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telebot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "callbacks.json")
+
+	testCallbackStore(t, func() telebot.CallbackStore {
+		return telebot.NewFileCallbackStore(path, time.Minute*2)
+	})
+
+	// data survives restart
+	ctx := telebot.WithClock(context.Background(),
+		telebot.ClockFunc(func() time.Time {
+			return time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+		}))
+	key, err := telebot.NewFileCallbackStore(path, time.Minute).
+		PutCallbackData(ctx, "restart")
+	require.NoError(t, err)
+	data, err := telebot.NewFileCallbackStore(path, time.Minute).
+		GetCallbackData(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "restart", data)
+
+	// keyboard is saved at once
+	long := strings.Repeat("a", 70)
+	markup, err := telegram.NewInlineKeyboardBuilder().
+		Callback("first", "1"+long).
+		Callback("second", "2"+long).
+		BuildWithStore(ctx, telebot.NewFileCallbackStore(path, time.Minute))
+	require.NoError(t, err)
+	restarted := telebot.NewFileCallbackStore(path, time.Minute)
+	for i, button := range markup.InlineKeyboard[0] {
+		data, err := restarted.GetCallbackData(ctx, strings.TrimPrefix(
+			button.CallbackData, telegram.StoredCallbackPrefix))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%d%s", i+1, long), data)
+	}
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("broken"), 0600))
+	_, err = telebot.NewFileCallbackStore(path, time.Minute).
+		GetCallbackData(ctx, key)
+	assert.Error(t, err)
+	_, err = telebot.NewFileCallbackStore(path, time.Minute).
+		PutCallbackData(ctx, "data")
+	assert.Error(t, err)
+}
+
+func TestCallbacks_stored(t *testing.T) {
+	ctx := context.Background()
+	store := telebot.NewMemoryCallbackStore(time.Hour)
+	var votes []vote
+	h := telebot.StoreCallbacks(store)(telebot.Callbacks(
+		map[string]telebot.InlineCallback{
+			"vote": telebot.PayloadCallback(
+				func(ctx context.Context, v vote) error {
+					assert.Equal(t, store, telebot.GetCallbackStore(ctx))
+					votes = append(votes, v)
+					return nil
+				}),
+		})(telebot.HandlerFunc(func(context.Context) error {
+		return fmt.Errorf("unexpected")
+	})))
+	handle := func(handler telebot.Handler, data string) error {
+		return handler.Handle(telebot.WithUpdate(ctx, &telegram.Update{
+			CallbackQuery: &telegram.CallbackQuery{Data: data},
+		}))
+	}
+
+	markup, err := telegram.NewInlineKeyboardBuilder().
+		Payload("Up", "vote", vote{PostID: 1 << 62, Up: true}).
+		Payload("Long", "vote", struct {
+			PostID int64
+			Up     bool
+			Text   string
+		}{PostID: 11, Text: strings.Repeat("a", 60)}).
+		BuildWithStore(ctx, store)
+	require.NoError(t, err)
+	buttons := markup.InlineKeyboard[0]
+	assert.False(t, strings.HasPrefix(buttons[0].CallbackData,
+		telegram.StoredCallbackPrefix))
+	assert.True(t, strings.HasPrefix(buttons[1].CallbackData,
+		telegram.StoredCallbackPrefix))
+
+	assert.NoError(t, handle(h, buttons[0].CallbackData))
+	assert.NoError(t, handle(h, buttons[1].CallbackData))
+	assert.Equal(t, []vote{{PostID: 1 << 62, Up: true}, {PostID: 11}}, votes)
+
+	assert.True(t, telebot.IsCallbackExpired(
+		handle(h, telegram.StoredCallbackPrefix+"unknown")))
+
+	// without store data is passed as is
+	h = telebot.Callbacks(map[string]telebot.InlineCallback{
+		"": telebot.CallbackFunc(
+			func(ctx context.Context, data string) error {
+				return fmt.Errorf("default %s", data)
+			}),
+	})(nil)
+	assert.EqualError(t, handle(h, telegram.StoredCallbackPrefix+"key:x"),
+		"default x")
+}
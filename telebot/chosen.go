@@ -0,0 +1,139 @@
+package telebot
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// ChosenHandler handles inline result chosen by user.
+// result is the result offered to user
+// or nil if it isn't remembered by ResultTracker.
+type ChosenHandler interface {
+	ChosenResult(ctx context.Context, chosen *telegram.ChosenInlineResult, result telegram.InlineQueryResult) error
+}
+
+// ChosenFunc defines a function to handle chosen inline results.
+// Implements ChosenHandler interface.
+type ChosenFunc func(ctx context.Context, chosen *telegram.ChosenInlineResult, result telegram.InlineQueryResult) error
+
+// ChosenResult method handles chosen inline result.
+func (f ChosenFunc) ChosenResult(ctx context.Context, chosen *telegram.ChosenInlineResult, result telegram.InlineQueryResult) error {
+	return f(ctx, chosen, result)
+}
+
+// ResultTracker remembers results of answered inline queries
+// for a window, so chosen results can be matched with them.
+// Results are remembered by user, query and result id.
+// Set it to InlineConfig.Tracker or call Remember
+// when answering inline queries in another way.
+type ResultTracker struct {
+	mu      sync.Mutex
+	window  time.Duration
+	results map[trackedKey]trackedResult
+}
+
+// NewResultTracker returns ResultTracker that remembers results
+// for window. Chosen inline result updates come soon after
+// query is answered, so a few minutes are usually enough.
+func NewResultTracker(window time.Duration) *ResultTracker {
+	return &ResultTracker{
+		window:  window,
+		results: map[trackedKey]trackedResult{},
+	}
+}
+
+// Remember remembers results offered to user in answer to query.
+// Results without ResultID method that can't be encoded
+// to json are skipped.
+func (t *ResultTracker) Remember(ctx context.Context, query *telegram.InlineQuery, results []telegram.InlineQueryResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := Now(ctx)
+	for key, item := range t.results {
+		if !now.Before(item.expires) {
+			delete(t.results, key)
+		}
+	}
+	for _, result := range results {
+		id, err := inlineResultID(result)
+		if err != nil {
+			continue
+		}
+		key := trackedKey{userID: query.From.ID, query: query.Query, id: id}
+		t.results[key] = trackedResult{
+			result:  result,
+			expires: now.Add(t.window),
+		}
+	}
+}
+
+// Result returns remembered result that user has chosen.
+func (t *ResultTracker) Result(ctx context.Context, chosen *telegram.ChosenInlineResult) (telegram.InlineQueryResult, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := trackedKey{
+		userID: chosen.From.ID,
+		query:  chosen.Query,
+		id:     chosen.ResultID,
+	}
+	item, ok := t.results[key]
+	if !ok || !Now(ctx).Before(item.expires) {
+		return nil, false
+	}
+	return item.result, true
+}
+
+// ChosenResults returns a middleware that passes chosen inline
+// results to handler with results remembered by tracker.
+// Other updates are passed to the next handler.
+// Use chosen.InlineMessageID to edit sent message.
+// Telegram sends chosen results only if inline feedback
+// is enabled for bot by @BotFather.
+func ChosenResults(tracker *ResultTracker, handler ChosenHandler) MiddlewareFunc {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			chosen := GetUpdate(ctx).ChosenInlineResult
+			if chosen == nil {
+				return next.Handle(ctx)
+			}
+			result, _ := tracker.Result(ctx, chosen)
+			return handler.ChosenResult(ctx, chosen, result)
+		})
+	}
+}
+
+// ============== Internal ================================================== //
+
+type trackedKey struct {
+	userID int64
+	query  string
+	id     string
+}
+
+type trackedResult struct {
+	result  telegram.InlineQueryResult
+	expires time.Time
+}
+
+// inlineResultID returns id of result of any type.
+// Results without ResultID method are encoded to json to find it.
+func inlineResultID(result telegram.InlineQueryResult) (string, error) {
+	if r, ok := result.(interface {
+		ResultID() string
+	}); ok {
+		return r.ResultID(), nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	var r struct {
+		ID string `json:"id"`
+	}
+	err = json.Unmarshal(data, &r)
+	return r.ID, err
+}
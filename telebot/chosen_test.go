@@ -0,0 +1,116 @@
+package telebot_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/m0sth8/httpmock"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestChosenResults(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	answers := mockInlineAnswers(t)
+
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := telebot.WithClock(
+		telebot.WithAPI(context.Background(), telegram.New("token")),
+		telebot.ClockFunc(func() time.Time { return now }))
+
+	tracker := telebot.NewResultTracker(time.Minute)
+	type choice struct {
+		chosen *telegram.ChosenInlineResult
+		result telegram.InlineQueryResult
+	}
+	var choices []choice
+	h := telebot.InlineQueriesWithConfig(telebot.InlineConfig{
+		Handler: telebot.InlineFunc(func(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, error) {
+			return numberResults(query, offset, 3), nil
+		}),
+		Tracker: tracker,
+	})(telebot.ChosenResults(tracker, telebot.ChosenFunc(
+		func(ctx context.Context, chosen *telegram.ChosenInlineResult, result telegram.InlineQueryResult) error {
+			choices = append(choices, choice{chosen, result})
+			return nil
+		}))(telebot.HandlerFunc(func(context.Context) error {
+		return fmt.Errorf("next")
+	})))
+	handle := func(update *telegram.Update) error {
+		return h.Handle(telebot.WithUpdate(ctx, update))
+	}
+
+	require.NoError(t, handle(&telegram.Update{
+		InlineQuery: &telegram.InlineQuery{
+			ID:    "1",
+			From:  telegram.User{ID: 42},
+			Query: "cats",
+		},
+	}))
+	assert.Len(t, answers.get(), 1)
+
+	chosen := &telegram.ChosenInlineResult{
+		ResultID:        "1",
+		From:            telegram.User{ID: 42},
+		Query:           "cats",
+		InlineMessageID: "inline",
+	}
+	require.NoError(t, handle(&telegram.Update{ChosenInlineResult: chosen}))
+	require.Len(t, choices, 1)
+	assert.Equal(t, chosen, choices[0].chosen)
+	assert.Equal(t, telegram.NewInlineQueryResultArticle("1", "cats", "cats"),
+		choices[0].result)
+
+	// results of another user or query aren't matched
+	for _, c := range []*telegram.ChosenInlineResult{
+		{ResultID: "1", From: telegram.User{ID: 43}, Query: "cats"},
+		{ResultID: "1", From: telegram.User{ID: 42}, Query: "dogs"},
+		{ResultID: "5", From: telegram.User{ID: 42}, Query: "cats"},
+	} {
+		require.NoError(t, handle(&telegram.Update{ChosenInlineResult: c}))
+	}
+	// results are forgotten after window
+	now = now.Add(time.Minute)
+	require.NoError(t, handle(&telegram.Update{ChosenInlineResult: chosen}))
+	require.Len(t, choices, 5)
+	for _, c := range choices[1:] {
+		assert.Nil(t, c.result)
+	}
+
+	assert.EqualError(t, handle(&telegram.Update{}), "next")
+}
+
+// customResult has no ResultID method.
+type customResult struct {
+	telegram.MarkInlineQueryResult
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+func TestResultTracker_Remember(t *testing.T) {
+	ctx := context.Background()
+	tracker := telebot.NewResultTracker(time.Minute)
+	query := &telegram.InlineQuery{From: telegram.User{ID: 42}, Query: "q"}
+	article := telegram.NewInlineQueryResultArticle("1", "title", "text")
+	custom := customResult{ID: "2", Type: "custom"}
+	tracker.Remember(ctx, query, []telegram.InlineQueryResult{
+		article, custom,
+	})
+	for id, exp := range map[string]telegram.InlineQueryResult{
+		"1": article,
+		"2": custom,
+	} {
+		result, ok := tracker.Result(ctx, &telegram.ChosenInlineResult{
+			ResultID: id,
+			From:     query.From,
+			Query:    query.Query,
+		})
+		assert.True(t, ok)
+		assert.Equal(t, exp, result)
+	}
+}
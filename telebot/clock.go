@@ -0,0 +1,62 @@
+package telebot
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type clockKey struct{}
+
+// Clock tells current time.
+// Use WithClock to replace system time, e.x. in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc implements Clock interface.
+type ClockFunc func() time.Time
+
+// Now returns current time.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// TimerClock is a Clock that runs functions after a duration
+// of its time. AfterFunc uses system timers for clocks
+// that don't implement it.
+type TimerClock interface {
+	Clock
+	// AfterFunc waits for d to elapse and calls f.
+	// It returns a function that stops the timer,
+	// it returns false if f has been called or timer is stopped.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// WithClock returns a new context with clock inside.
+// Use Now to take current time from context.
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// Now returns current time of a clock from context
+// or time.Now() if context doesn't have a clock.
+// Handlers and middleware should use it instead of time.Now().
+func Now(ctx context.Context) time.Time {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// AfterFunc calls f after d elapses on a clock from context
+// or calls it in its own goroutine by time.AfterFunc
+// if the clock isn't a TimerClock. It returns a function
+// that stops the timer, see TimerClock.
+// Middleware should use it instead of time.AfterFunc.
+func AfterFunc(ctx context.Context, d time.Duration, f func()) (stop func() bool) {
+	if clock, ok := ctx.Value(clockKey{}).(TimerClock); ok {
+		return clock.AfterFunc(d, f)
+	}
+	return time.AfterFunc(d, f).Stop
+}
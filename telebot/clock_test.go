@@ -0,0 +1,20 @@
+package telebot_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram/telebot"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestNow(t *testing.T) {
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := telebot.WithClock(context.Background(),
+		telebot.ClockFunc(func() time.Time { return now }))
+	assert.Equal(t, now, telebot.Now(ctx))
+
+	before := time.Now()
+	assert.False(t, telebot.Now(context.Background()).Before(before))
+}
@@ -0,0 +1,233 @@
+package telebot
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// MaxInlineResults is a maximum number of results
+// in an answer to inline query.
+const MaxInlineResults = 50
+
+// InlineHandler returns results of inline query starting from offset.
+// It should return more than MaxInlineResults results if there are
+// more of them, e.x. up to MaxInlineResults+1, then InlineQueries
+// middleware sends the first MaxInlineResults and sets next offset.
+type InlineHandler interface {
+	InlineQuery(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, error)
+}
+
+// InlineFunc defines a function to handle inline queries.
+// Implements InlineHandler interface.
+type InlineFunc func(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, error)
+
+// InlineQuery method handles inline query.
+func (f InlineFunc) InlineQuery(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, error) {
+	return f(ctx, query, offset)
+}
+
+// InlineConfig helps to configure InlineQueries middleware.
+type InlineConfig struct {
+	// Handler returns results of inline queries, required.
+	Handler InlineHandler
+	// Debounce is a delay before query is handled. Query is dropped
+	// if the same user sends a new one during it, so handler isn't
+	// called on every keystroke. Debounced queries are handled
+	// by AfterFunc after middleware returns, their errors are passed
+	// to ErrorFunc.
+	// Zero debounce disables it.
+	Debounce time.Duration
+	// ErrorFunc handles errors of debounced queries.
+	// Errors are logged by default.
+	ErrorFunc ErrorFunc
+	// CacheTime is a time results are cached by middleware
+	// and telegram servers. Zero disables local cache and
+	// telegram uses its default cache time.
+	CacheTime time.Duration
+	// IsPersonal caches results for every user separately.
+	IsPersonal bool
+	// Tracker remembers answered results
+	// for ChosenResults middleware. Optional.
+	Tracker *ResultTracker
+}
+
+// InlineQueries returns a middleware that answers inline queries
+// by handler. Other updates are passed to the next handler.
+func InlineQueries(handler InlineHandler) MiddlewareFunc {
+	return InlineQueriesWithConfig(InlineConfig{
+		Handler: handler,
+	})
+}
+
+// InlineQueriesWithConfig takes InlineConfig and returns
+// InlineQueries middleware. Offset of query is decoded to a number
+// of results that user has already got, it's 0 for the first page.
+// Results are sliced to MaxInlineResults and next offset is set
+// if handler returned more. Query is answered with empty results
+// if there are no more of them, so client stops waiting.
+func InlineQueriesWithConfig(cfg InlineConfig) MiddlewareFunc {
+	if cfg.ErrorFunc == nil {
+		cfg.ErrorFunc = func(ctx context.Context, err error) {
+			log.Printf("inline query error: %s", err.Error())
+		}
+	}
+	s := &inlineState{
+		cfg:     cfg,
+		pending: map[int64]string{},
+		cache:   map[inlineCacheKey]inlineCacheItem{},
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			query := GetUpdate(ctx).InlineQuery
+			if query == nil {
+				return next.Handle(ctx)
+			}
+			offset, _ := strconv.Atoi(query.Offset)
+			if offset < 0 {
+				offset = 0
+			}
+			if results, ok := s.cached(ctx, query, offset); ok {
+				s.drop(query.From.ID)
+				return s.answer(ctx, query, offset, results)
+			}
+			if cfg.Debounce <= 0 {
+				return s.handle(ctx, query, offset)
+			}
+			s.supersede(query)
+			AfterFunc(ctx, cfg.Debounce, func() {
+				if !s.isLatest(query) {
+					return
+				}
+				if err := s.handle(ctx, query, offset); err != nil {
+					cfg.ErrorFunc(ctx, err)
+				}
+			})
+			return nil
+		})
+	}
+}
+
+// ============== Internal ================================================== //
+
+type inlineCacheKey struct {
+	userID int64
+	query  string
+	offset int
+}
+
+type inlineCacheItem struct {
+	results []telegram.InlineQueryResult
+	expires time.Time
+}
+
+type inlineState struct {
+	cfg InlineConfig
+
+	mu sync.Mutex
+	// pending keeps id of the latest query of every user
+	pending map[int64]string
+	cache   map[inlineCacheKey]inlineCacheItem
+}
+
+// supersede makes query the latest one of its user,
+// so debounced queries of the user are dropped.
+func (s *inlineState) supersede(query *telegram.InlineQuery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[query.From.ID] = query.ID
+}
+
+// drop drops debounced queries of user.
+func (s *inlineState) drop(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, userID)
+}
+
+// isLatest returns true if query is the latest one of its user
+// and forgets it.
+func (s *inlineState) isLatest(query *telegram.InlineQuery) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[query.From.ID] != query.ID {
+		return false
+	}
+	delete(s.pending, query.From.ID)
+	return true
+}
+
+func (s *inlineState) handle(ctx context.Context, query *telegram.InlineQuery, offset int) error {
+	results, err := s.cfg.Handler.InlineQuery(ctx, query, offset)
+	if err != nil {
+		return err
+	}
+	s.store(ctx, query, offset, results)
+	return s.answer(ctx, query, offset, results)
+}
+
+func (s *inlineState) answer(ctx context.Context, query *telegram.InlineQuery, offset int, results []telegram.InlineQueryResult) error {
+	if results == nil {
+		results = []telegram.InlineQueryResult{}
+	}
+	cfg := telegram.AnswerInlineQueryCfg{
+		InlineQueryID: query.ID,
+		Results:       results,
+		CacheTime:     int(s.cfg.CacheTime / time.Second),
+		IsPersonal:    s.cfg.IsPersonal,
+	}
+	if len(results) > MaxInlineResults {
+		cfg.Results = results[:MaxInlineResults]
+		cfg.NextOffset = strconv.Itoa(offset + MaxInlineResults)
+	}
+	if _, err := GetAPI(ctx).AnswerInlineQuery(ctx, cfg); err != nil {
+		return err
+	}
+	if s.cfg.Tracker != nil {
+		s.cfg.Tracker.Remember(ctx, query, cfg.Results)
+	}
+	return nil
+}
+
+func (s *inlineState) cacheKey(query *telegram.InlineQuery, offset int) inlineCacheKey {
+	key := inlineCacheKey{query: query.Query, offset: offset}
+	if s.cfg.IsPersonal {
+		key.userID = query.From.ID
+	}
+	return key
+}
+
+func (s *inlineState) cached(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, bool) {
+	if s.cfg.CacheTime <= 0 {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.cache[s.cacheKey(query, offset)]
+	if !ok || !Now(ctx).Before(item.expires) {
+		return nil, false
+	}
+	return item.results, true
+}
+
+func (s *inlineState) store(ctx context.Context, query *telegram.InlineQuery, offset int, results []telegram.InlineQueryResult) {
+	if s.cfg.CacheTime <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := Now(ctx)
+	for key, item := range s.cache {
+		if !now.Before(item.expires) {
+			delete(s.cache, key)
+		}
+	}
+	s.cache[s.cacheKey(query, offset)] = inlineCacheItem{
+		results: results,
+		expires: now.Add(s.cfg.CacheTime),
+	}
+}
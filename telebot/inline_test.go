@@ -0,0 +1,195 @@
+package telebot_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/telebot/telebottest"
+	"github.com/m0sth8/httpmock"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+type inlineAnswers struct {
+	mu      sync.Mutex
+	answers []url.Values
+}
+
+func (a *inlineAnswers) get() []url.Values {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]url.Values(nil), a.answers...)
+}
+
+func mockInlineAnswers(t *testing.T) *inlineAnswers {
+	a := &inlineAnswers{}
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bottoken/answerInlineQuery",
+		func(req *http.Request) (*http.Response, error) {
+			require.NoError(t, req.ParseForm())
+			a.mu.Lock()
+			a.answers = append(a.answers, req.PostForm)
+			a.mu.Unlock()
+			return httpmock.NewStringResponder(200,
+				`{"ok":true,"result":true}`)(req)
+		},
+	)
+	return a
+}
+
+func resultIDs(t *testing.T, answer url.Values) []string {
+	var results []struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(answer.Get("results")), &results))
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func numberResults(query *telegram.InlineQuery, offset, count int) []telegram.InlineQueryResult {
+	var results []telegram.InlineQueryResult
+	for i := offset; i < count && i <= offset+telebot.MaxInlineResults; i++ {
+		results = append(results, telegram.NewInlineQueryResultArticle(
+			strconv.Itoa(i), query.Query, query.Query))
+	}
+	return results
+}
+
+func TestInlineQueries(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	answers := mockInlineAnswers(t)
+
+	now := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := telebot.WithClock(
+		telebot.WithAPI(context.Background(), telegram.New("token")),
+		telebot.ClockFunc(func() time.Time { return now }))
+
+	var calls []int
+	h := telebot.InlineQueriesWithConfig(telebot.InlineConfig{
+		Handler: telebot.InlineFunc(func(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, error) {
+			if query.Query == "fail" {
+				return nil, fmt.Errorf("fail")
+			}
+			calls = append(calls, offset)
+			return numberResults(query, offset, 120), nil
+		}),
+		CacheTime: time.Minute,
+	})(telebot.HandlerFunc(func(context.Context) error {
+		return fmt.Errorf("next")
+	}))
+	handle := func(id, text, offset string) error {
+		return h.Handle(telebot.WithUpdate(ctx, &telegram.Update{
+			InlineQuery: &telegram.InlineQuery{
+				ID:     id,
+				From:   telegram.User{ID: 42},
+				Query:  text,
+				Offset: offset,
+			},
+		}))
+	}
+
+	require.NoError(t, handle("1", "q", ""))
+	require.NoError(t, handle("2", "q", "50"))
+	require.NoError(t, handle("3", "q", "100"))
+	all := answers.get()
+	require.Len(t, all, 3)
+	assert.Equal(t, "1", all[0].Get("inline_query_id"))
+	assert.Equal(t, "50", all[0].Get("next_offset"))
+	assert.Equal(t, "60", all[0].Get("cache_time"))
+	assert.Len(t, resultIDs(t, all[0]), 50)
+	assert.Equal(t, "50", resultIDs(t, all[1])[0])
+	assert.Equal(t, "100", all[1].Get("next_offset"))
+	assert.Len(t, resultIDs(t, all[2]), 20)
+	assert.Equal(t, "", all[2].Get("next_offset"))
+	assert.Equal(t, []int{0, 50, 100}, calls)
+
+	// results are cached
+	require.NoError(t, handle("4", "q", ""))
+	assert.Equal(t, []int{0, 50, 100}, calls)
+	now = now.Add(time.Minute)
+	require.NoError(t, handle("5", "q", ""))
+	assert.Equal(t, []int{0, 50, 100, 0}, calls)
+	assert.Len(t, answers.get(), 5)
+
+	// bad offset is the first page
+	require.NoError(t, handle("6", "q", "bad"))
+	assert.Equal(t, "6", answers.get()[5].Get("inline_query_id"))
+	assert.Equal(t, []int{0, 50, 100, 0}, calls)
+
+	// the last page is empty, so client stops waiting for it
+	require.NoError(t, handle("7", "q", "120"))
+	assert.Equal(t, "[]", answers.get()[6].Get("results"))
+	assert.Equal(t, "", answers.get()[6].Get("next_offset"))
+
+	assert.EqualError(t, handle("8", "fail", ""), "fail")
+	assert.EqualError(t, h.Handle(telebot.WithUpdate(ctx, &telegram.Update{})),
+		"next")
+}
+
+func TestInlineQueries_debounce(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	answers := mockInlineAnswers(t)
+	clock := telebottest.NewClock(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	ctx := telebot.WithClock(
+		telebot.WithAPI(context.Background(), telegram.New("token")), clock)
+
+	var queries []string
+	var errs []error
+	h := telebot.InlineQueriesWithConfig(telebot.InlineConfig{
+		Handler: telebot.InlineFunc(func(ctx context.Context, query *telegram.InlineQuery, offset int) ([]telegram.InlineQueryResult, error) {
+			queries = append(queries, query.Query)
+			if query.Query == "fail" {
+				return nil, fmt.Errorf("fail")
+			}
+			return numberResults(query, offset, 1), nil
+		}),
+		Debounce: time.Millisecond * 50,
+		ErrorFunc: func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		},
+	})(nil)
+	handle := func(userID int64, id, text string) {
+		require.NoError(t, h.Handle(telebot.WithUpdate(ctx, &telegram.Update{
+			InlineQuery: &telegram.InlineQuery{
+				ID:    id,
+				From:  telegram.User{ID: userID},
+				Query: text,
+			},
+		})))
+	}
+
+	handle(42, "1", "c")
+	handle(42, "2", "ca")
+	handle(43, "3", "dog")
+	handle(42, "4", "cat")
+	clock.Advance(time.Millisecond * 49)
+	assert.Empty(t, answers.get())
+	clock.Advance(time.Millisecond)
+	all := answers.get()
+	require.Len(t, all, 2)
+	assert.Equal(t, "3", all[0].Get("inline_query_id"))
+	assert.Equal(t, "4", all[1].Get("inline_query_id"))
+	assert.Equal(t, []string{"dog", "cat"}, queries)
+
+	handle(42, "5", "fail")
+	assert.Empty(t, errs)
+	clock.Advance(time.Millisecond * 50)
+	if assert.Len(t, errs, 1) {
+		assert.EqualError(t, errs[0], "fail")
+	}
+}
@@ -0,0 +1,174 @@
+package telebot
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// UpdateJournal keeps received updates until they are handled,
+// so a crashed process can replay them on restart.
+type UpdateJournal interface {
+	// Append stores update before it's handled.
+	Append(ctx context.Context, update telegram.Update) error
+	// Ack marks update as handled.
+	Ack(ctx context.Context, updateID int64) error
+	// Pending returns stored updates that weren't acknowledged,
+	// ordered by update id.
+	Pending(ctx context.Context) ([]telegram.Update, error)
+}
+
+// FileJournal is an UpdateJournal that appends records to a file,
+// one json object per line. File is truncated when all updates
+// are acknowledged and it has at least CompactSize records.
+type FileJournal struct {
+	// CompactSize is a number of records after that file is truncated
+	// if there are no pending updates.
+	// Default value is set by OpenFileJournal as 1000.
+	CompactSize int
+
+	mu      sync.Mutex
+	f       *os.File
+	records int
+	pending map[int64]telegram.Update
+}
+
+type journalRecord struct {
+	Update *telegram.Update `json:"update,omitempty"`
+	Ack    int64            `json:"ack,omitempty"`
+}
+
+// OpenFileJournal opens or creates journal file and reads
+// pending updates from it.
+func OpenFileJournal(path string) (*FileJournal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	j := &FileJournal{
+		CompactSize: 1000,
+		f:           f,
+		pending:     make(map[int64]telegram.Update),
+	}
+	if err := j.read(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append writes update to file and syncs it.
+func (j *FileJournal) Append(_ context.Context, update telegram.Update) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record := journalRecord{Update: &update}
+	if err := j.write(record); err != nil {
+		return err
+	}
+	if err := j.f.Sync(); err != nil {
+		return err
+	}
+	j.apply(record)
+	return nil
+}
+
+// Ack writes acknowledgement to file.
+// It isn't synced, so an update can be replayed twice after crash.
+func (j *FileJournal) Ack(_ context.Context, updateID int64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.pending[updateID]; !ok {
+		return nil
+	}
+	if len(j.pending) == 1 && j.records+1 >= j.CompactSize {
+		// nothing is pending after this ack, start from scratch
+		if err := j.f.Truncate(0); err != nil {
+			return err
+		}
+		j.records = 0
+		delete(j.pending, updateID)
+		return nil
+	}
+	record := journalRecord{Ack: updateID}
+	if err := j.write(record); err != nil {
+		return err
+	}
+	j.apply(record)
+	return nil
+}
+
+// Pending returns updates that weren't acknowledged.
+func (j *FileJournal) Pending(context.Context) ([]telegram.Update, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	updates := make([]telegram.Update, 0, len(j.pending))
+	for _, update := range j.pending {
+		updates = append(updates, update)
+	}
+	sort.Sort(updatesByID(updates))
+	return updates, nil
+}
+
+// Close closes journal file.
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
+
+// ============== Internal ================================================== //
+
+// read reads records from file. A partially written record
+// at the end of file (e.x. after crash) is truncated.
+func (j *FileJournal) read() error {
+	r := bufio.NewReader(j.f)
+	var size int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) == 0 {
+				return nil
+			}
+			return j.f.Truncate(size)
+		}
+		if err != nil {
+			return err
+		}
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return j.f.Truncate(size)
+		}
+		j.apply(record)
+		size += int64(len(line))
+	}
+}
+
+func (j *FileJournal) write(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = j.f.Write(append(data, '\n'))
+	return err
+}
+
+func (j *FileJournal) apply(record journalRecord) {
+	j.records++
+	if record.Update != nil {
+		j.pending[record.Update.UpdateID] = *record.Update
+	} else {
+		delete(j.pending, record.Ack)
+	}
+}
+
+type updatesByID []telegram.Update
+
+func (u updatesByID) Len() int           { return len(u) }
+func (u updatesByID) Less(i, j int) bool { return u[i].UpdateID < u[j].UpdateID }
+func (u updatesByID) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
@@ -0,0 +1,96 @@
+package telebot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestFileJournal(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "telebot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	j, err := telebot.OpenFileJournal(path)
+	require.NoError(t, err)
+	pending, err := j.Pending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	for _, id := range []int64{12, 10, 11} {
+		require.NoError(t, j.Append(ctx, telegram.Update{
+			UpdateID: id,
+			Message:  &telegram.Message{Text: "message"},
+		}))
+	}
+	require.NoError(t, j.Ack(ctx, 11))
+	// unknown ids are ignored
+	require.NoError(t, j.Ack(ctx, 100))
+	require.NoError(t, j.Close())
+
+	// simulate crash during the last write
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"update":{"update_id":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j, err = telebot.OpenFileJournal(path)
+	require.NoError(t, err)
+	defer j.Close()
+	pending, err = j.Pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []telegram.Update{
+		{UpdateID: 10, Message: &telegram.Message{Text: "message"}},
+		{UpdateID: 12, Message: &telegram.Message{Text: "message"}},
+	}, pending)
+
+	// partial record is truncated, so new records are readable
+	require.NoError(t, j.Append(ctx, telegram.Update{UpdateID: 13}))
+	j2, err := telebot.OpenFileJournal(path)
+	require.NoError(t, err)
+	pending, err = j2.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 3)
+	require.NoError(t, j2.Close())
+}
+
+func TestFileJournal_compact(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "telebot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "journal")
+
+	j, err := telebot.OpenFileJournal(path)
+	require.NoError(t, err)
+	defer j.Close()
+	j.CompactSize = 4
+
+	require.NoError(t, j.Append(ctx, telegram.Update{UpdateID: 1}))
+	require.NoError(t, j.Ack(ctx, 1))
+	require.NoError(t, j.Append(ctx, telegram.Update{UpdateID: 2}))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.NotZero(t, info.Size())
+
+	// the 4th record and nothing is pending
+	require.NoError(t, j.Ack(ctx, 2))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	require.NoError(t, j.Append(ctx, telegram.Update{UpdateID: 3}))
+	pending, err := j.Pending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []telegram.Update{{UpdateID: 3}}, pending)
+}
@@ -0,0 +1,356 @@
+package telebot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// MenuAction runs when menu item is pressed.
+type MenuAction func(ctx context.Context) error
+
+// MenuItem is a button of a menu.
+// Pressing it opens Submenu or runs Action,
+// URL item opens url instead.
+type MenuItem struct {
+	Text    string
+	Submenu *Menu
+	// Action runs when item is pressed,
+	// then the current menu is rendered again.
+	Action MenuAction
+	URL    string
+}
+
+// Menu is a node of a menu tree.
+type Menu struct {
+	// ID identifies menu in callback data,
+	// it must be short and unique in a tree.
+	ID string
+	// Text of the message with menu.
+	Text string
+	// TextFunc returns text at render time instead of Text. Optional.
+	TextFunc func(ctx context.Context) (string, error)
+	// ParseMode of Text. Optional.
+	ParseMode string
+	// Items are buttons of menu.
+	Items []MenuItem
+	// ItemsFunc returns items at render time,
+	// they go after Items. It must return the same items
+	// for the same state, because items are found by index
+	// when a button is pressed. Optional.
+	ItemsFunc func(ctx context.Context) ([]MenuItem, error)
+	// Columns is a number of item buttons in a row, 1 by default.
+	Columns int
+}
+
+// MenuConfig helps to configure MenuTree.
+type MenuConfig struct {
+	// Prefix of menu callback data, required.
+	// MenuTree must be registered in Callbacks middleware with it.
+	Prefix string
+	// Root is a home menu, required.
+	Root *Menu
+	// BackText is a text of a button that opens the previous menu,
+	// "« Back" by default.
+	BackText string
+	// HomeText is a text of a button that opens Root,
+	// "« Home" by default.
+	HomeText string
+	// TTL is a time navigation stack of a message is kept
+	// after it was used last time, 24 hours by default.
+	// Buttons of a message with forgotten stack open Root.
+	TTL time.Duration
+}
+
+// MenuTree shows menus in a message and edits it in place
+// when user navigates between them. A navigation stack is kept
+// in memory for every user and message until TTL passes.
+// Back button is shown in submenus and Home button is shown
+// deeper than the first level.
+//
+//	menu := telebot.NewMenuTree(telebot.MenuConfig{
+//		Prefix: "menu",
+//		Root: &telebot.Menu{
+//			ID:   "home",
+//			Text: "Settings",
+//			Items: []telebot.MenuItem{
+//				{Text: "Language", Submenu: languages},
+//			},
+//		},
+//	})
+//	bot.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+//		"menu": menu,
+//	}))
+//
+//	// in a handler
+//	_, err := menu.Send(ctx, chatID)
+//
+// If context has CallbackStore (see StoreCallbacks),
+// it's used for long callback data.
+type MenuTree struct {
+	cfg MenuConfig
+
+	mu     sync.Mutex
+	stacks map[menuKey]menuStack
+	// sweepAt is time to remove expired stacks
+	sweepAt time.Time
+}
+
+// NewMenuTree returns MenuTree with cfg.
+// Zero fields of cfg are replaced by default values.
+func NewMenuTree(cfg MenuConfig) *MenuTree {
+	if cfg.BackText == "" {
+		cfg.BackText = "« Back"
+	}
+	if cfg.HomeText == "" {
+		cfg.HomeText = "« Home"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Hour * 24
+	}
+	return &MenuTree{
+		cfg:    cfg,
+		stacks: map[menuKey]menuStack{},
+	}
+}
+
+// Send sends root menu to chat with a new navigation stack.
+func (m *MenuTree) Send(ctx context.Context, chatID int64) (*telegram.Message, error) {
+	stack := []*Menu{m.cfg.Root}
+	text, markup, err := m.render(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	cfg := telegram.NewMessage(chatID, text)
+	cfg.ParseMode = m.cfg.Root.ParseMode
+	cfg.ReplyMarkup = markup
+	msg, err := GetAPI(ctx).SendMessage(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.setStack(ctx, menuKey{
+		userID:    userID(ctx),
+		chatID:    msg.Chat.ID,
+		messageID: msg.MessageID,
+	}, stack)
+	return msg, nil
+}
+
+// Callback handles pressed menu button: opens submenu,
+// runs action or goes back, then edits the message
+// by EditMessageText and answers callback query.
+func (m *MenuTree) Callback(ctx context.Context, data string) error {
+	api := GetAPI(ctx)
+	query := GetUpdate(ctx).CallbackQuery
+	stack, err := m.navigate(ctx, data)
+	if err != nil {
+		return err
+	}
+	text, markup, err := m.render(ctx, stack)
+	if err != nil {
+		return err
+	}
+	cfg := telegram.EditMessageTextCfg{
+		BaseEdit:  queryEdit(query, markup),
+		Text:      text,
+		ParseMode: stack[len(stack)-1].ParseMode,
+	}
+	if _, err := api.EditMessageText(ctx, cfg); err != nil && !isNotModified(err) {
+		return err
+	}
+	return AnswerCallback(ctx, telegram.NewAnswerCallback(query.ID, ""))
+}
+
+// ============== Internal ================================================== //
+
+const (
+	menuBack = "b"
+	menuHome = "h"
+)
+
+func userID(ctx context.Context) int64 {
+	if user := GetUpdate(ctx).From(); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// menuKey identifies navigation stack of user in a message.
+type menuKey struct {
+	userID          int64
+	chatID          int64
+	messageID       int64
+	inlineMessageID string
+}
+
+type menuStack struct {
+	menus   []*Menu
+	expires time.Time
+}
+
+// queryMenuKey returns key of the message with pressed button.
+func queryMenuKey(query *telegram.CallbackQuery) menuKey {
+	key := menuKey{
+		userID:          query.From.ID,
+		inlineMessageID: query.InlineMessageID,
+	}
+	if query.Message != nil {
+		key.chatID = query.Message.Chat.ID
+		key.messageID = query.Message.MessageID
+	}
+	return key
+}
+
+func (m *MenuTree) getStack(ctx context.Context, key menuKey) []*Menu {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stack, ok := m.stacks[key]
+	if !ok || !Now(ctx).Before(stack.expires) {
+		return nil
+	}
+	return stack.menus
+}
+
+// setStack saves stack and removes expired stacks once in TTL.
+func (m *MenuTree) setStack(ctx context.Context, key menuKey, menus []*Menu) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := Now(ctx)
+	if !now.Before(m.sweepAt) {
+		for k, stack := range m.stacks {
+			if !now.Before(stack.expires) {
+				delete(m.stacks, k)
+			}
+		}
+		m.sweepAt = now.Add(m.cfg.TTL)
+	}
+	m.stacks[key] = menuStack{
+		menus:   menus,
+		expires: now.Add(m.cfg.TTL),
+	}
+}
+
+// navigate changes navigation stack by callback data
+// "menuID:item index", "menuID:b" or "menuID:h" and returns it.
+// Button of a menu that isn't in stack, e.x. pressed in a message
+// with forgotten stack, opens root menu.
+func (m *MenuTree) navigate(ctx context.Context, data string) ([]*Menu, error) {
+	key := queryMenuKey(GetUpdate(ctx).CallbackQuery)
+	root := []*Menu{m.cfg.Root}
+	parts := strings.SplitN(data, ":", 2)
+	if len(parts) != 2 {
+		m.setStack(ctx, key, root)
+		return root, nil
+	}
+	id, op := parts[0], parts[1]
+
+	stack := m.getStack(ctx, key)
+	depth := len(stack)
+	for depth > 0 && stack[depth-1].ID != id {
+		depth--
+	}
+	if depth == 0 {
+		m.setStack(ctx, key, root)
+		return root, nil
+	}
+	// copy stack, so concurrent callbacks don't change it
+	stack = append([]*Menu(nil), stack[:depth]...)
+
+	switch op {
+	case menuBack:
+		if len(stack) > 1 {
+			stack = stack[:len(stack)-1]
+		}
+	case menuHome:
+		stack = stack[:1]
+	default:
+		index, err := strconv.Atoi(op)
+		if err != nil {
+			return nil, err
+		}
+		items, err := menuItems(ctx, stack[len(stack)-1])
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(items) {
+			break
+		}
+		item := items[index]
+		if item.Submenu != nil {
+			stack = append(stack, item.Submenu)
+		} else if item.Action != nil {
+			if err := item.Action(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m.setStack(ctx, key, stack)
+	return stack, nil
+}
+
+// render returns text and keyboard of the last menu in stack.
+func (m *MenuTree) render(ctx context.Context, stack []*Menu) (string, *telegram.InlineKeyboardMarkup, error) {
+	menu := stack[len(stack)-1]
+	text := menu.Text
+	if menu.TextFunc != nil {
+		var err error
+		if text, err = menu.TextFunc(ctx); err != nil {
+			return "", nil, err
+		}
+	}
+	items, err := menuItems(ctx, menu)
+	if err != nil {
+		return "", nil, err
+	}
+
+	prefix := m.cfg.Prefix + ":" + menu.ID + ":"
+	b := telegram.NewInlineKeyboardBuilder()
+	for i, item := range items {
+		if item.URL != "" {
+			b.URL(item.Text, item.URL)
+			continue
+		}
+		b.Callback(item.Text, prefix+strconv.Itoa(i))
+	}
+	columns := menu.Columns
+	if columns <= 0 {
+		columns = 1
+	}
+	b.Grid(columns)
+	if len(stack) > 1 {
+		b.Callback(m.cfg.BackText, prefix+menuBack)
+	}
+	if len(stack) > 2 {
+		b.Callback(m.cfg.HomeText, prefix+menuHome)
+	}
+
+	var markup *telegram.InlineKeyboardMarkup
+	if store := GetCallbackStore(ctx); store != nil {
+		markup, err = b.BuildWithStore(ctx, store)
+	} else {
+		markup, err = b.Build()
+	}
+	return text, markup, err
+}
+
+func menuItems(ctx context.Context, menu *Menu) ([]MenuItem, error) {
+	if menu.ItemsFunc == nil {
+		return menu.Items, nil
+	}
+	items, err := menu.ItemsFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]MenuItem(nil), menu.Items...), items...), nil
+}
+
+// isNotModified returns true if message is edited
+// with the same content.
+func isNotModified(err error) bool {
+	apiErr, ok := err.(*telegram.APIError)
+	return ok && strings.Contains(apiErr.Description, "message is not modified")
+}
@@ -0,0 +1,150 @@
+package telebot_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/telebot/telebottest"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestMenuTree(t *testing.T) {
+	language := "en"
+	languages := &telebot.Menu{
+		ID: "lang",
+		TextFunc: func(ctx context.Context) (string, error) {
+			return "Language: " + language, nil
+		},
+		ItemsFunc: func(ctx context.Context) ([]telebot.MenuItem, error) {
+			var items []telebot.MenuItem
+			for _, lang := range []string{"en", "de", "fr"} {
+				if lang == language {
+					continue
+				}
+				lang := lang
+				items = append(items, telebot.MenuItem{
+					Text: "Use " + lang,
+					Action: func(ctx context.Context) error {
+						language = lang
+						return nil
+					},
+				})
+			}
+			return items, nil
+		},
+		Columns: 2,
+	}
+	menu := telebot.NewMenuTree(telebot.MenuConfig{
+		Prefix: "menu",
+		Root: &telebot.Menu{
+			ID:   "home",
+			Text: "Home",
+			Items: []telebot.MenuItem{
+				{Text: "Settings", Submenu: &telebot.Menu{
+					ID:   "settings",
+					Text: "Settings",
+					Items: []telebot.MenuItem{
+						{Text: "Language", Submenu: languages},
+						{Text: "Fail", Action: func(context.Context) error {
+							return fmt.Errorf("action error")
+						}},
+					},
+				}},
+				{Text: "Help", URL: "https://example.com/help"},
+			},
+		},
+	})
+	var errs []error
+	tt := telebottest.New(t, func(api *telegram.API) *telebot.Bot {
+		b := telebot.NewWithAPI(api)
+		b.ErrorFunc(func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		})
+		b.Use(telebot.Commands(map[string]telebot.Commander{
+			"menu": telebot.CommandFunc(
+				func(ctx context.Context, arg string) error {
+					_, err := menu.Send(ctx,
+						telebot.GetUpdate(ctx).Message.Chat.ID)
+					return err
+				}),
+		}))
+		b.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+			"menu": menu,
+		}))
+		return b
+	})
+	defer tt.Close()
+	keyboard := func(msg telegram.Message) [][]string {
+		return buttonTexts(&telegram.InlineKeyboardMarkup{
+			InlineKeyboard: tt.Server.InlineKeyboard(msg),
+		})
+	}
+
+	user := tt.User(42)
+	user.Sends("/menu")
+	first := user.ExpectMessage(telebottest.Text("Home"))
+	msg := first
+	assert.Equal(t, [][]string{{"Settings"}, {"Help"}}, keyboard(msg))
+
+	user.Presses("Settings")
+	user.ExpectAnswer("")
+	msg = user.ExpectEdited(telebottest.Text("Settings"))
+	assert.Equal(t, [][]string{{"Language"}, {"Fail"}, {"« Back"}}, keyboard(msg))
+
+	user.Presses("Language")
+	msg = user.ExpectEdited(telebottest.Text("Language: en"))
+	assert.Equal(t, [][]string{{"Use de", "Use fr"}, {"« Back", "« Home"}},
+		keyboard(msg))
+
+	// dynamic items are rendered again after action
+	user.Presses("Use fr")
+	msg = user.ExpectEdited(telebottest.Text("Language: fr"))
+	assert.Equal(t, [][]string{{"Use en", "Use de"}, {"« Back", "« Home"}},
+		keyboard(msg))
+
+	user.Presses("« Back")
+	user.ExpectEdited(telebottest.Text("Settings"))
+	user.Presses("Language")
+	user.Presses("« Home")
+	user.ExpectEdited(telebottest.Text("Home"))
+
+	// navigation stack is kept per user
+	other := tt.User(43)
+	other.Sends("/menu")
+	other.ExpectMessage(telebottest.Text("Home"))
+	user.Presses("Settings")
+	other.Presses("Settings")
+	user.Presses("Language")
+	user.ExpectEdited(telebottest.Text("Language: fr"))
+	other.ExpectEdited(telebottest.Text("Settings"))
+
+	assert.Empty(t, errs)
+	user.Presses("« Back")
+	user.Presses("Fail")
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "action error")
+
+	// navigation stack is kept per message
+	user.Presses("Language")
+	user.ExpectEdited(telebottest.Text("Language: fr"))
+	user.Sends("/menu")
+	user.ExpectMessage(telebottest.Text("Home"))
+	pressFirst := func(data string) string {
+		tt.Server.PressButton(first, user.User, data)
+		tt.Run()
+		return tt.Server.BotMessages(user.ChatID)[0].Text
+	}
+	assert.Equal(t, "Settings", pressFirst("menu:lang:b"))
+
+	// stack is forgotten after TTL
+	pressFirst("menu:settings:0")
+	tt.Clock.Advance(time.Hour * 24)
+	assert.Equal(t, "Home", pressFirst("menu:lang:b"))
+
+	user.ExpectNoMessages()
+	other.ExpectNoMessages()
+}
@@ -1,8 +1,11 @@
 package telebot
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 
+	"github.com/bot-api/telegram"
 	"golang.org/x/net/context"
 )
 
@@ -94,6 +97,9 @@ func Commands(commands map[string]Commander) MiddlewareFunc {
 // Callback path is divided by ":".
 // Empty callback (e.x. "": InlineCallback) used as a default callback handler.
 // Nil callback (e.x. "smth": nil) used as an EmptyHandler
+// Data that starts with telegram.StoredCallbackPrefix is taken
+// from CallbackStore in context (see StoreCallbacks),
+// ErrCallbackExpired is returned if it's not there anymore.
 // Take a look on examples/callbacks/main.go to know more.
 func Callbacks(callbacks map[string]InlineCallback) MiddlewareFunc {
 	return func(next Handler) Handler {
@@ -102,7 +108,19 @@ func Callbacks(callbacks map[string]InlineCallback) MiddlewareFunc {
 			if update.CallbackQuery == nil {
 				return next.Handle(ctx)
 			}
-			queryData := strings.SplitN(update.CallbackQuery.Data, ":", 2)
+			callbackData := update.CallbackQuery.Data
+			store := GetCallbackStore(ctx)
+			if store != nil && strings.HasPrefix(
+				callbackData, telegram.StoredCallbackPrefix) {
+
+				var err error
+				callbackData, err = store.GetCallbackData(ctx,
+					strings.TrimPrefix(callbackData, telegram.StoredCallbackPrefix))
+				if err != nil {
+					return err
+				}
+			}
+			queryData := strings.SplitN(callbackData, ":", 2)
 			var prefix, data string
 			switch len(queryData) {
 			case 2:
@@ -126,3 +144,62 @@ func Callbacks(callbacks map[string]InlineCallback) MiddlewareFunc {
 		})
 	}
 }
+
+// ReplyButtons middleware takes map of reply keyboard button labels.
+// It runs associated Handler if update message text is equal
+// to a label, e.x. a button of telegram.ReplyKeyboardBuilder is pressed.
+// Nil handler (e.x. "Help": nil) passes update to the next handler.
+func ReplyButtons(buttons map[string]Handler) MiddlewareFunc {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			update := GetUpdate(ctx)
+			if update.Message == nil || update.Message.Text == "" {
+				return next.Handle(ctx)
+			}
+			handler, ok := buttons[update.Message.Text]
+			if !ok || handler == nil {
+				return next.Handle(ctx)
+			}
+			return handler.Handle(ctx)
+		})
+	}
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// PayloadCallback returns InlineCallback that decodes callback data
+// by telegram.DecodeCallbackData and passes payload to fn.
+// fn must be a function like func(context.Context, T) error,
+// where T is a type of payload encoded by telegram.EncodeCallbackData,
+// PayloadCallback panics otherwise. Use it with Callbacks middleware:
+//
+//	telebot.Callbacks(map[string]telebot.InlineCallback{
+//		"vote": telebot.PayloadCallback(
+//			func(ctx context.Context, vote Vote) error {
+//				// handle vote
+//			}),
+//	})
+func PayloadCallback(fn interface{}) InlineCallback {
+	f := reflect.ValueOf(fn)
+	t := f.Type()
+	if t.Kind() != reflect.Func ||
+		t.NumIn() != 2 || t.In(0) != contextType ||
+		t.NumOut() != 1 || t.Out(0) != errorType {
+		panic(fmt.Sprintf(
+			"telebot: PayloadCallback takes func(context.Context, T) error, got %T",
+			fn))
+	}
+	payloadType := t.In(1)
+	return CallbackFunc(func(ctx context.Context, data string) error {
+		payload := reflect.New(payloadType)
+		if err := telegram.DecodeCallbackData(data, payload.Interface()); err != nil {
+			return err
+		}
+		out := f.Call([]reflect.Value{reflect.ValueOf(ctx), payload.Elem()})
+		err, _ := out[0].Interface().(error)
+		return err
+	})
+}
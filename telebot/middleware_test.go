@@ -141,3 +141,81 @@ func TestCommands(t *testing.T) {
 		assert.Equal(t, c(f).Handle(ctx), hErr)
 	}
 }
+
+type vote struct {
+	PostID int64
+	Up     bool
+}
+
+func TestPayloadCallback(t *testing.T) {
+	var votes []vote
+	c := telebot.Callbacks(map[string]telebot.InlineCallback{
+		"vote": telebot.PayloadCallback(
+			func(ctx context.Context, v vote) error {
+				votes = append(votes, v)
+				return nil
+			}),
+		"ptr": telebot.PayloadCallback(
+			func(ctx context.Context, v *vote) error {
+				votes = append(votes, *v)
+				return fmt.Errorf("ptr error")
+			}),
+	})
+	h := c(telebot.HandlerFunc(func(context.Context) error {
+		return fmt.Errorf("unexpected")
+	}))
+	handle := func(data string) error {
+		return h.Handle(telebot.WithUpdate(context.Background(), &telegram.Update{
+			CallbackQuery: &telegram.CallbackQuery{Data: data},
+		}))
+	}
+
+	data, err := telegram.EncodeCallbackData("vote", vote{PostID: 10, Up: true})
+	assert.NoError(t, err)
+	assert.NoError(t, handle(data))
+	assert.EqualError(t, handle("ptr:[11]"), "ptr error")
+	assert.Error(t, handle("vote:broken"))
+	assert.Equal(t, []vote{{PostID: 10, Up: true}, {PostID: 11}}, votes)
+
+	assert.Panics(t, func() {
+		telebot.PayloadCallback(func(v vote) error { return nil })
+	})
+	assert.Panics(t, func() {
+		telebot.PayloadCallback(func(ctx context.Context, v vote) {})
+	})
+	assert.Panics(t, func() {
+		telebot.PayloadCallback("not a func")
+	})
+}
+
+func TestReplyButtons(t *testing.T) {
+	var pressed []string
+	button := func(label string) telebot.Handler {
+		return telebot.HandlerFunc(func(context.Context) error {
+			pressed = append(pressed, label)
+			return nil
+		})
+	}
+	nextErr := fmt.Errorf("next")
+	h := telebot.ReplyButtons(map[string]telebot.Handler{
+		"Settings": button("Settings"),
+		"Help":     button("Help"),
+		"Skip":     nil,
+	})(telebot.HandlerFunc(func(context.Context) error {
+		return nextErr
+	}))
+	handle := func(update *telegram.Update) error {
+		return h.Handle(telebot.WithUpdate(context.Background(), update))
+	}
+	text := func(text string) *telegram.Update {
+		return &telegram.Update{Message: &telegram.Message{Text: text}}
+	}
+
+	assert.NoError(t, handle(text("Settings")))
+	assert.NoError(t, handle(text("Help")))
+	assert.Equal(t, nextErr, handle(text("help")))
+	assert.Equal(t, nextErr, handle(text("Skip")))
+	assert.Equal(t, nextErr, handle(text("")))
+	assert.Equal(t, nextErr, handle(&telegram.Update{}))
+	assert.Equal(t, []string{"Settings", "Help"}, pressed)
+}
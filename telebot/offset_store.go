@@ -0,0 +1,169 @@
+package telebot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// OffsetStore keeps an offset of the next update to process,
+// so bot continues from it after restart.
+// Updates with ids lower than the offset are considered processed.
+type OffsetStore interface {
+	// Offset returns stored offset or 0 if nothing is stored yet.
+	Offset(ctx context.Context) (int64, error)
+	// Commit stores offset. Offsets lower than stored are ignored.
+	Commit(ctx context.Context, offset int64) error
+}
+
+// MemoryOffsetStore keeps offset in memory.
+// It's useful for tests and for SkipProcessed middleware
+// in a single process.
+type MemoryOffsetStore struct {
+	mu     sync.Mutex
+	offset int64
+}
+
+// NewMemoryOffsetStore returns empty MemoryOffsetStore.
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{}
+}
+
+// Offset returns stored offset.
+func (s *MemoryOffsetStore) Offset(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset, nil
+}
+
+// Commit stores offset if it's greater than stored one.
+func (s *MemoryOffsetStore) Commit(_ context.Context, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset > s.offset {
+		s.offset = offset
+	}
+	return nil
+}
+
+// FileOffsetStore keeps offset in a file as a decimal number.
+// File is replaced atomically on every commit.
+type FileOffsetStore struct {
+	mu     sync.Mutex
+	path   string
+	offset int64
+	loaded bool
+}
+
+// NewFileOffsetStore returns FileOffsetStore that keeps offset in path.
+// File is created on the first commit.
+func NewFileOffsetStore(path string) *FileOffsetStore {
+	return &FileOffsetStore{
+		path: path,
+	}
+}
+
+// Offset returns offset stored in file or 0 if file doesn't exist.
+func (s *FileOffsetStore) Offset(context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return 0, err
+	}
+	return s.offset, nil
+}
+
+// Commit writes offset to file if it's greater than stored one.
+func (s *FileOffsetStore) Commit(_ context.Context, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if offset <= s.offset {
+		return nil
+	}
+	if err := s.write(offset); err != nil {
+		return err
+	}
+	s.offset = offset
+	return nil
+}
+
+// SkipProcessed returns a middleware that skips updates
+// with ids lower than offset in store, e.x. updates redelivered
+// by webhook. It commits the next offset after handler
+// returns without error, so it works for updates
+// from polling, webhook and HandleUpdate alike.
+func SkipProcessed(store OffsetStore) MiddlewareFunc {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			offset, err := store.Offset(ctx)
+			if err != nil {
+				return err
+			}
+			id := GetUpdate(ctx).UpdateID
+			if id < offset {
+				return nil
+			}
+			if err = next.Handle(ctx); err != nil {
+				return err
+			}
+			return store.Commit(ctx, id+1)
+		})
+	}
+}
+
+// ============== Internal ================================================== //
+
+func (s *FileOffsetStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		s.offset, err = strconv.ParseInt(
+			strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return err
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *FileOffsetStore) write(offset int64) error {
+	return writeFileAtomic(s.path, []byte(strconv.FormatInt(offset, 10)))
+}
+
+// writeFileAtomic writes data to a temporary file in the same
+// directory and renames it to path, so path has either old
+// or new data after a crash.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
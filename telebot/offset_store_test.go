@@ -0,0 +1,111 @@
+package telebot_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func testOffsetStore(t *testing.T, store telebot.OffsetStore) {
+	ctx := context.Background()
+
+	offset, err := store.Offset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), offset)
+
+	require.NoError(t, store.Commit(ctx, 10))
+	offset, err = store.Offset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+
+	// lower offsets are ignored
+	require.NoError(t, store.Commit(ctx, 5))
+	offset, err = store.Offset(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+}
+
+func TestMemoryOffsetStore(t *testing.T) {
+	testOffsetStore(t, telebot.NewMemoryOffsetStore())
+}
+
+func TestFileOffsetStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telebot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "offset")
+
+	testOffsetStore(t, telebot.NewFileOffsetStore(path))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "10", string(data))
+
+	// offset survives restart
+	offset, err := telebot.NewFileOffsetStore(path).Offset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), offset)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("broken"), 0600))
+	_, err = telebot.NewFileOffsetStore(path).Offset(context.Background())
+	assert.Error(t, err)
+	err = telebot.NewFileOffsetStore(path).Commit(context.Background(), 20)
+	assert.Error(t, err)
+}
+
+func TestSkipProcessed(t *testing.T) {
+	store := telebot.NewMemoryOffsetStore()
+	require.NoError(t, store.Commit(context.Background(), 10))
+
+	var handled []int64
+	h := telebot.SkipProcessed(store)(telebot.HandlerFunc(
+		func(ctx context.Context) error {
+			id := telebot.GetUpdate(ctx).UpdateID
+			handled = append(handled, id)
+			if id == 12 {
+				return fmt.Errorf("handler error")
+			}
+			return nil
+		}))
+	for _, id := range []int64{9, 10, 11, 10, 11} {
+		ctx := telebot.WithUpdate(context.Background(),
+			&telegram.Update{UpdateID: id})
+		require.NoError(t, h.Handle(ctx))
+	}
+	assert.Equal(t, []int64{10, 11}, handled)
+
+	// failed update isn't committed
+	for i := 0; i < 2; i++ {
+		ctx := telebot.WithUpdate(context.Background(),
+			&telegram.Update{UpdateID: 12})
+		assert.EqualError(t, h.Handle(ctx), "handler error")
+	}
+	assert.Equal(t, []int64{10, 11, 12, 12}, handled)
+	offset, err := store.Offset(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), offset)
+}
+
+type brokenOffsetStore struct{}
+
+func (brokenOffsetStore) Offset(context.Context) (int64, error) {
+	return 0, fmt.Errorf("offset error")
+}
+
+func (brokenOffsetStore) Commit(context.Context, int64) error {
+	return fmt.Errorf("commit error")
+}
+
+func TestSkipProcessed_error(t *testing.T) {
+	h := telebot.SkipProcessed(brokenOffsetStore{})(telebot.EmptyHandler())
+	ctx := telebot.WithUpdate(context.Background(), &telegram.Update{})
+	assert.EqualError(t, h.Handle(ctx), "offset error")
+}
@@ -0,0 +1,204 @@
+package telebot
+
+import (
+	"strconv"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// PageFunc returns buttons of items in [offset, offset+limit)
+// and total number of items.
+type PageFunc func(ctx context.Context, offset, limit int) ([]telegram.InlineKeyboardButton, int, error)
+
+// PaginatorConfig helps to configure Paginator.
+type PaginatorConfig struct {
+	// Prefix of paginator callback data, required.
+	// Paginator must be registered in Callbacks middleware with it.
+	Prefix string
+	// Page returns item buttons of a page, required.
+	Page PageFunc
+	// PageSize is a number of items on a page, 10 by default.
+	PageSize int
+	// Columns is a number of item buttons in a row, 1 by default.
+	Columns int
+	// Pages is a maximum number of page number buttons,
+	// 5 by default and 6 at most, so navigation row fits
+	// into 8 buttons. The first and the last pages are
+	// always shown if Pages is at least 3.
+	Pages int
+	// PrevText and NextText are texts of buttons
+	// that switch to the previous and the next pages.
+	// "‹" and "›" by default.
+	PrevText string
+	NextText string
+}
+
+// Paginator renders a page of items as inline keyboard
+// with navigation row of prev, next and page number buttons.
+// It handles its own callbacks and edits keyboard of the original
+// message, so it should be added to Callbacks middleware:
+//
+//	pages := telebot.NewPaginator(telebot.PaginatorConfig{
+//		Prefix: "items",
+//		Page:   itemButtons,
+//	})
+//	bot.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+//		"items": pages,
+//	}))
+//
+//	// in a handler
+//	markup, err := pages.Markup(ctx, 0)
+type Paginator struct {
+	cfg PaginatorConfig
+}
+
+// NewPaginator returns Paginator with cfg.
+// Zero fields of cfg are replaced by default values.
+func NewPaginator(cfg PaginatorConfig) *Paginator {
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = 10
+	}
+	if cfg.Columns <= 0 {
+		cfg.Columns = 1
+	}
+	if cfg.Pages <= 0 {
+		cfg.Pages = 5
+	}
+	if cfg.Pages > maxNavButtons-2 {
+		// prev and next buttons take the rest of the row
+		cfg.Pages = maxNavButtons - 2
+	}
+	if cfg.PrevText == "" {
+		cfg.PrevText = "‹"
+	}
+	if cfg.NextText == "" {
+		cfg.NextText = "›"
+	}
+	return &Paginator{cfg: cfg}
+}
+
+// Markup returns keyboard with items of page, pages are numbered from 0.
+// Page out of range is replaced by the closest one.
+func (p *Paginator) Markup(ctx context.Context, page int) (*telegram.InlineKeyboardMarkup, error) {
+	if page < 0 {
+		page = 0
+	}
+	buttons, total, err := p.cfg.Page(ctx, page*p.cfg.PageSize, p.cfg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	pages := (total + p.cfg.PageSize - 1) / p.cfg.PageSize
+	if page >= pages && pages > 0 {
+		return p.Markup(ctx, pages-1)
+	}
+
+	b := telegram.NewInlineKeyboardBuilder()
+	for _, button := range buttons {
+		b.Button(button)
+	}
+	b.Grid(p.cfg.Columns)
+	if pages <= 1 {
+		return b.Build()
+	}
+
+	if page > 0 {
+		b.Callback(p.cfg.PrevText, p.data(page-1))
+	}
+	for _, i := range pageNumbers(page, pages, p.cfg.Pages) {
+		if i == page {
+			// pressing current page doesn't change anything
+			b.Callback("· "+strconv.Itoa(i+1)+" ·", p.cfg.Prefix)
+			continue
+		}
+		b.Callback(strconv.Itoa(i+1), p.data(i))
+	}
+	if page < pages-1 {
+		b.Callback(p.cfg.NextText, p.data(page+1))
+	}
+	return b.Build()
+}
+
+// Callback switches page of the message with pressed button
+// by EditMessageReplyMarkup and answers callback query.
+func (p *Paginator) Callback(ctx context.Context, data string) error {
+	api := GetAPI(ctx)
+	query := GetUpdate(ctx).CallbackQuery
+	if data != "" {
+		page, err := strconv.Atoi(data)
+		if err != nil {
+			return err
+		}
+		markup, err := p.Markup(ctx, page)
+		if err != nil {
+			return err
+		}
+		cfg := telegram.EditMessageReplyMarkupCfg{
+			BaseEdit: queryEdit(query, markup),
+		}
+		if _, err := api.EditMessageReplyMarkup(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	return AnswerCallback(ctx, telegram.NewAnswerCallback(query.ID, ""))
+}
+
+// ============== Internal ================================================== //
+
+// maxNavButtons is a maximum number of buttons in navigation row,
+// telegram shows at most 8 buttons in a row.
+const maxNavButtons = 8
+
+func (p *Paginator) data(page int) string {
+	return p.cfg.Prefix + ":" + strconv.Itoa(page)
+}
+
+// queryEdit returns BaseEdit of a message with callback query button.
+func queryEdit(query *telegram.CallbackQuery, markup *telegram.InlineKeyboardMarkup) telegram.BaseEdit {
+	edit := telegram.BaseEdit{
+		InlineMessageID: query.InlineMessageID,
+		ReplyMarkup:     markup,
+	}
+	if query.Message != nil {
+		edit.ChatID = query.Message.Chat.ID
+		edit.MessageID = query.Message.MessageID
+	}
+	return edit
+}
+
+// pageNumbers returns at most size page numbers to show around page.
+// The first and the last pages are included if size is at least 3.
+func pageNumbers(page, pages, size int) []int {
+	if size >= pages {
+		return pageRange(0, pages)
+	}
+	if size < 3 {
+		return pageRange(windowStart(page, 0, pages, size), size)
+	}
+	inner := size - 2
+	numbers := []int{0}
+	numbers = append(numbers,
+		pageRange(windowStart(page, 1, pages-1, inner), inner)...)
+	return append(numbers, pages-1)
+}
+
+// windowStart returns the first page of size pages around page
+// within [min, max).
+func windowStart(page, min, max, size int) int {
+	first := page - (size-1)/2
+	if first > max-size {
+		first = max - size
+	}
+	if first < min {
+		first = min
+	}
+	return first
+}
+
+func pageRange(first, size int) []int {
+	numbers := make([]int, size)
+	for i := range numbers {
+		numbers[i] = first + i
+	}
+	return numbers
+}
@@ -0,0 +1,181 @@
+package telebot_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/telebot/telebottest"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func itemPages(count int) telebot.PageFunc {
+	return func(ctx context.Context, offset, limit int) ([]telegram.InlineKeyboardButton, int, error) {
+		var buttons []telegram.InlineKeyboardButton
+		for i := offset; i < offset+limit && i < count; i++ {
+			buttons = append(buttons, telegram.InlineKeyboardButton{
+				Text:         fmt.Sprintf("item %d", i),
+				CallbackData: "item:" + strconv.Itoa(i),
+			})
+		}
+		return buttons, count, nil
+	}
+}
+
+func buttonTexts(markup *telegram.InlineKeyboardMarkup) [][]string {
+	var rows [][]string
+	for _, row := range markup.InlineKeyboard {
+		var texts []string
+		for _, b := range row {
+			texts = append(texts, b.Text)
+		}
+		rows = append(rows, texts)
+	}
+	return rows
+}
+
+func TestPaginator_Markup(t *testing.T) {
+	ctx := context.Background()
+	p := telebot.NewPaginator(telebot.PaginatorConfig{
+		Prefix:   "items",
+		Page:     itemPages(50),
+		PageSize: 3,
+		Columns:  2,
+	})
+
+	markup, err := p.Markup(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"item 0", "item 1"},
+		{"item 2"},
+		{"· 1 ·", "2", "3", "4", "17", "›"},
+	}, buttonTexts(markup))
+	nav := markup.InlineKeyboard[2]
+	assert.Equal(t, "items", nav[0].CallbackData)
+	assert.Equal(t, "items:1", nav[1].CallbackData)
+	assert.Equal(t, "items:1", nav[5].CallbackData)
+
+	markup, err = p.Markup(ctx, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"‹", "1", "8", "· 9 ·", "10", "17", "›"},
+		buttonTexts(markup)[2])
+	assert.Equal(t, "items:7", markup.InlineKeyboard[2][0].CallbackData)
+
+	// out of range pages
+	markup, err = p.Markup(ctx, 100)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"item 48", "item 49"},
+		{"‹", "1", "14", "15", "16", "· 17 ·"},
+	}, buttonTexts(markup))
+	markup, err = p.Markup(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "item 0", markup.InlineKeyboard[0][0].Text)
+
+	// a single page has no navigation
+	markup, err = telebot.NewPaginator(telebot.PaginatorConfig{
+		Prefix: "items",
+		Page:   itemPages(2),
+	}).Markup(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"item 0"}, {"item 1"}}, buttonTexts(markup))
+
+	_, err = telebot.NewPaginator(telebot.PaginatorConfig{
+		Prefix: "items",
+		Page: func(context.Context, int, int) ([]telegram.InlineKeyboardButton, int, error) {
+			return nil, 0, fmt.Errorf("page error")
+		},
+	}).Markup(ctx, 0)
+	assert.EqualError(t, err, "page error")
+}
+
+func TestPaginator_Markup_manyPages(t *testing.T) {
+	ctx := context.Background()
+	p := telebot.NewPaginator(telebot.PaginatorConfig{
+		Prefix:   "items",
+		Page:     itemPages(1000),
+		PageSize: 1,
+		Pages:    20,
+	})
+	for _, tt := range []struct {
+		page int
+		nav  []string
+	}{
+		{0, []string{"· 1 ·", "2", "3", "4", "5", "1000", "›"}},
+		{1, []string{"‹", "1", "· 2 ·", "3", "4", "5", "1000", "›"}},
+		{500, []string{"‹", "1", "500", "· 501 ·", "502", "503", "1000", "›"}},
+		{999, []string{"‹", "1", "996", "997", "998", "999", "· 1000 ·"}},
+	} {
+		markup, err := p.Markup(ctx, tt.page)
+		require.NoError(t, err)
+		rows := buttonTexts(markup)
+		assert.Equal(t, tt.nav, rows[len(rows)-1])
+	}
+
+	// small windows don't show the first and the last pages
+	markup, err := telebot.NewPaginator(telebot.PaginatorConfig{
+		Prefix:   "items",
+		Page:     itemPages(10),
+		PageSize: 1,
+		Pages:    2,
+	}).Markup(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"‹", "· 6 ·", "7", "›"}, buttonTexts(markup)[1])
+}
+
+func TestPaginator_Callback(t *testing.T) {
+	p := telebot.NewPaginator(telebot.PaginatorConfig{
+		Prefix:   "items",
+		Page:     itemPages(7),
+		PageSize: 3,
+	})
+	tt := telebottest.New(t, func(api *telegram.API) *telebot.Bot {
+		b := telebot.NewWithAPI(api)
+		b.Use(telebot.Commands(map[string]telebot.Commander{
+			"list": telebot.CommandFunc(
+				func(ctx context.Context, arg string) error {
+					markup, err := p.Markup(ctx, 0)
+					if err != nil {
+						return err
+					}
+					cfg := telegram.NewMessage(
+						telebot.GetUpdate(ctx).Message.Chat.ID, "Items")
+					cfg.ReplyMarkup = markup
+					_, err = telebot.GetAPI(ctx).SendMessage(ctx, cfg)
+					return err
+				}),
+		}))
+		b.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+			"items": p,
+		}))
+		return b
+	})
+	defer tt.Close()
+
+	user := tt.User(42)
+	user.Sends("/list")
+	user.ExpectMessage(telebottest.Text("Items"),
+		telebottest.HasButton("item 0"), telebottest.HasButton("›"))
+
+	user.Presses("›")
+	user.ExpectAnswer("")
+	user.ExpectEdited(telebottest.Text("Items"),
+		telebottest.HasButton("item 3"), telebottest.HasButton("· 2 ·"))
+
+	user.Presses("3")
+	user.ExpectAnswer("")
+	msg := user.ExpectEdited(telebottest.HasButton("item 6"))
+	assert.Equal(t, [][]string{{"item 6"}, {"‹", "1", "2", "· 3 ·"}},
+		buttonTexts(&telegram.InlineKeyboardMarkup{
+			InlineKeyboard: tt.Server.InlineKeyboard(msg),
+		}))
+
+	user.Presses("· 3 ·")
+	user.ExpectAnswer("")
+	assert.Len(t, tt.Server.Calls("editMessageReplyMarkup"), 2)
+	user.ExpectNoMessages()
+}
@@ -0,0 +1,182 @@
+package telebot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// Record is a line of a recording.
+// It contains either a received update
+// or an API call with its response.
+type Record struct {
+	// Update is a received update.
+	Update *telegram.Update `json:"update,omitempty"`
+
+	// Method is a name of called API method, e.x. sendMessage.
+	Method string `json:"method,omitempty"`
+	// Params are method params. Uploaded files are recorded
+	// as "@filename" values.
+	Params url.Values `json:"params,omitempty"`
+	// Status is a response status code.
+	Status int `json:"status,omitempty"`
+	// Body is a response body.
+	Body string `json:"body,omitempty"`
+}
+
+// A Recorder writes received updates and API calls
+// to w as json lines. Use Recorder.Middleware to record updates
+// and Recorder.Client as API http client to record calls:
+//
+//	rec := telebot.NewRecorder(f)
+//	api := telegram.NewWithClient(token, rec.Client(http.DefaultClient))
+//	bot := telebot.NewWithAPI(api)
+//	bot.Use(rec.Middleware())
+//
+// getUpdates calls and file downloads aren't recorded.
+// Calls are recorded after the update they're made for,
+// as long as updates are handled one by one
+// and handlers don't call API in background.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder returns a Recorder that writes to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Middleware returns a middleware that records every update.
+func (r *Recorder) Middleware() MiddlewareFunc {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context) error {
+			r.write(Record{Update: GetUpdate(ctx)})
+			return next.Handle(ctx)
+		})
+	}
+}
+
+// Client returns telegram.HTTPDoer that records calls made by client.
+func (r *Recorder) Client(client telegram.HTTPDoer) telegram.HTTPDoer {
+	return recordingClient{client: client, recorder: r}
+}
+
+// ReadRecording reads records written by Recorder.
+func ReadRecording(rd io.Reader) ([]Record, error) {
+	var records []Record
+	br := bufio.NewReader(rd)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record Record
+			if jErr := json.Unmarshal(line, &record); jErr != nil {
+				return nil, jErr
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ============== Internal ================================================== //
+
+func (r *Recorder) write(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(record); err != nil {
+		log.Printf("recorder error: %s", err.Error())
+	}
+}
+
+type recordingClient struct {
+	client   telegram.HTTPDoer
+	recorder *Recorder
+}
+
+func (c recordingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != "POST" {
+		// file download
+		return c.client.Do(req)
+	}
+	record, err := requestRecord(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil || record.Method == "getUpdates" {
+		return resp, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	record.Status = resp.StatusCode
+	record.Body = string(body)
+	c.recorder.write(record)
+	return resp, nil
+}
+
+// requestRecord returns record with method name and params of req.
+// Request body is restored to be sent.
+func requestRecord(req *http.Request) (Record, error) {
+	record := Record{
+		Method: path.Base(req.URL.Path),
+	}
+	if req.Body == nil {
+		return record, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return record, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var mediaType string
+	var params map[string]string
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		mediaType, params, err = mime.ParseMediaType(ct)
+		if err != nil {
+			return record, err
+		}
+	}
+	if mediaType != "multipart/form-data" {
+		record.Params, err = url.ParseQuery(string(body))
+		return record, err
+	}
+	form, err := multipart.NewReader(
+		bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		return record, err
+	}
+	defer form.RemoveAll()
+	record.Params = url.Values(form.Value)
+	for name, files := range form.File {
+		for _, file := range files {
+			record.Params.Add(name, "@"+file.Filename)
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,146 @@
+package telebot
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/m0sth8/httpmock"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func echoHandler(prefix string) HandlerFunc {
+	return func(ctx context.Context) error {
+		msg := GetUpdate(ctx).Message
+		_, err := GetAPI(ctx).SendMessage(ctx,
+			telegram.NewMessage(msg.Chat.ID, prefix+msg.Text))
+		return err
+	}
+}
+
+func TestRecorder_Replayer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+
+	client := &http.Client{}
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/sendMessage",
+		NewAPIResponder(200, telegram.Message{MessageID: 1}),
+	)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/sendPhoto",
+		NewAPIResponder(200, telegram.Message{MessageID: 2}),
+	)
+
+	updates := []telegram.Update{
+		{
+			UpdateID: 10,
+			Message: &telegram.Message{
+				Chat: telegram.Chat{ID: 1},
+				Text: "hello",
+			},
+		},
+		{
+			UpdateID: 11,
+			Message: &telegram.Message{
+				Chat: telegram.Chat{ID: 1},
+				Text: "photo",
+			},
+		},
+	}
+
+	// record
+	buf := &bytes.Buffer{}
+	rec := NewRecorder(buf)
+	b := NewWithAPI(telegram.NewWithClient("_token", rec.Client(client)))
+	b.Use(rec.Middleware())
+	b.Handle(recordedHandler(t))
+	for i := range updates {
+		b.handleUpdate(ctx, &updates[i])
+	}
+	assert.NotContains(t, buf.String(), "_token")
+
+	records, err := ReadRecording(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, updates[0], *records[0].Update)
+	assert.Equal(t, "sendMessage", records[1].Method)
+	assert.Equal(t, "echo: hello", records[1].Params.Get("text"))
+	assert.Equal(t, http.StatusOK, records[1].Status)
+	assert.Contains(t, records[1].Body, `"message_id":1`)
+	assert.Equal(t, "sendPhoto", records[3].Method)
+	assert.Equal(t, url.Values{
+		"chat_id": {"1"},
+		"photo":   {"@photo.jpg"},
+	}, records[3].Params)
+
+	// replay with the same handler, responses are taken from recording
+	rp := NewReplayer(records)
+	b = NewWithAPI(telegram.NewWithClient("_token", rp))
+	b.Handle(recordedHandler(t))
+	diffs, err := rp.Replay(ctx, b)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// replay with a changed handler
+	rp = NewReplayer(records)
+	b = NewWithAPI(telegram.NewWithClient("_token", rp))
+	b.HandleFunc(echoHandler("> "))
+	diffs, err = rp.Replay(ctx, b)
+	require.NoError(t, err)
+	require.Len(t, diffs, 2)
+	assert.Equal(t, int64(10), diffs[0].UpdateID)
+	assert.Equal(t, "> hello", diffs[0].Actual.Params.Get("text"))
+	assert.Equal(t,
+		"update 11, call #0: expected sendPhoto "+
+			"map[chat_id:[1] photo:[@photo.jpg]], "+
+			"got sendMessage map[chat_id:[1] text:[> photo]]",
+		diffs[1].String())
+
+	// unexpected and missing calls
+	rp = NewReplayer(records)
+	b = NewWithAPI(telegram.NewWithClient("_token", rp))
+	b.HandleFunc(func(ctx context.Context) error {
+		if GetUpdate(ctx).UpdateID == 10 {
+			return nil
+		}
+		_, err := GetAPI(ctx).Send(ctx, telegram.NewPhotoShare(1, "id"))
+		if err != nil {
+			return err
+		}
+		_, err = GetAPI(ctx).Send(ctx, telegram.NewPhotoShare(1, "id"))
+		return err
+	})
+	diffs, err = rp.Replay(ctx, b)
+	require.NoError(t, err)
+	require.Len(t, diffs, 3)
+	assert.True(t, strings.HasPrefix(diffs[0].String(),
+		"update 10, call #0: missing sendMessage"), diffs[0].String())
+	assert.True(t, strings.HasPrefix(diffs[2].String(),
+		"update 11, call #1: unexpected sendPhoto"), diffs[2].String())
+}
+
+// recordedHandler echoes text messages and uploads a photo
+// if message text is "photo".
+func recordedHandler(t *testing.T) Handler {
+	return HandlerFunc(func(ctx context.Context) error {
+		if GetUpdate(ctx).Message.Text == "photo" {
+			msg, err := GetAPI(ctx).Send(ctx, telegram.NewPhotoUpload(1,
+				telegram.NewBytesFile("photo.jpg", []byte("data"))))
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), msg.MessageID)
+			return nil
+		}
+		return echoHandler("echo: ")(ctx)
+	})
+}
@@ -0,0 +1,159 @@
+package telebot
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// ReplayDiff describes a difference between recorded
+// and replayed API calls made for an update.
+type ReplayDiff struct {
+	UpdateID int64
+	// Index is a number of the call made for the update.
+	Index int
+	// Expected is a recorded call or nil if the call is unexpected.
+	Expected *Record
+	// Actual is a replayed call or nil if the call isn't made.
+	Actual *Record
+}
+
+// String returns human readable representation of ReplayDiff.
+func (d ReplayDiff) String() string {
+	switch {
+	case d.Expected == nil:
+		return fmt.Sprintf("update %d, call #%d: unexpected %s %v",
+			d.UpdateID, d.Index, d.Actual.Method, d.Actual.Params)
+	case d.Actual == nil:
+		return fmt.Sprintf("update %d, call #%d: missing %s %v",
+			d.UpdateID, d.Index, d.Expected.Method, d.Expected.Params)
+	}
+	return fmt.Sprintf("update %d, call #%d: expected %s %v, got %s %v",
+		d.UpdateID, d.Index,
+		d.Expected.Method, d.Expected.Params,
+		d.Actual.Method, d.Actual.Params)
+}
+
+// A Replayer feeds recorded updates through a bot and compares
+// API calls with recorded ones. Replayer is a fake telegram.HTTPDoer
+// that answers calls with recorded responses, use it as API client:
+//
+//	records, err := telebot.ReadRecording(f)
+//	rp := telebot.NewReplayer(records)
+//	bot := telebot.NewWithAPI(telegram.NewWithClient("token", rp))
+//	// setup bot handlers and middleware
+//	diffs, err := rp.Replay(ctx, bot)
+type Replayer struct {
+	records []Record
+
+	mu       sync.Mutex
+	expected []Record
+	actual   []Record
+}
+
+// NewReplayer returns a Replayer for records.
+func NewReplayer(records []Record) *Replayer {
+	return &Replayer{
+		records: records,
+	}
+}
+
+// Replay handles every recorded update by bot and returns
+// differences between recorded and replayed calls.
+// Calls recorded before the first update are ignored.
+func (r *Replayer) Replay(ctx context.Context, b *Bot) ([]ReplayDiff, error) {
+	var diffs []ReplayDiff
+	for i := 0; i < len(r.records); i++ {
+		if r.records[i].Update == nil {
+			continue
+		}
+		update := *r.records[i].Update
+		var expected []Record
+		for i+1 < len(r.records) && r.records[i+1].Update == nil {
+			i++
+			expected = append(expected, r.records[i])
+		}
+
+		if err := ctx.Err(); err != nil {
+			return diffs, err
+		}
+		r.mu.Lock()
+		r.expected, r.actual = expected, nil
+		r.mu.Unlock()
+
+		b.handleUpdate(ctx, &update)
+
+		r.mu.Lock()
+		actual := r.actual
+		r.mu.Unlock()
+		diffs = append(diffs, diffCalls(update.UpdateID, expected, actual)...)
+	}
+	return diffs, nil
+}
+
+// Do answers request with a recorded response of a call with the same
+// index and method. Unexpected calls are answered with an empty result.
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	record, err := requestRecord(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	index := len(r.actual)
+	r.actual = append(r.actual, record)
+	var expected *Record
+	if index < len(r.expected) && r.expected[index].Method == record.Method {
+		expected = &r.expected[index]
+	}
+	r.mu.Unlock()
+
+	status, body := http.StatusOK, `{"ok":true,"result":{}}`
+	if expected != nil {
+		body = expected.Body
+		if expected.Status != 0 {
+			status = expected.Status
+		}
+	}
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+		},
+		Body:    ioutil.NopCloser(bytes.NewBufferString(body)),
+		Request: req,
+	}, nil
+}
+
+// ============== Internal ================================================== //
+
+func diffCalls(updateID int64, expected, actual []Record) []ReplayDiff {
+	var diffs []ReplayDiff
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		diff := ReplayDiff{UpdateID: updateID, Index: i}
+		if i < len(expected) {
+			diff.Expected = &expected[i]
+		}
+		if i < len(actual) {
+			diff.Actual = &actual[i]
+		}
+		if diff.Expected != nil && diff.Actual != nil &&
+			sameCall(*diff.Expected, *diff.Actual) {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+func sameCall(a, b Record) bool {
+	if a.Method != b.Method || len(a.Params) != len(b.Params) {
+		return false
+	}
+	return len(a.Params) == 0 || reflect.DeepEqual(a.Params, b.Params)
+}
@@ -2,6 +2,8 @@ package telebot
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 
 	"encoding/json"
 
@@ -9,13 +11,57 @@ import (
 )
 
 type sessionKey struct{}
+type typedSessionKey struct{}
+
+// ErrSessionConflict should be returned by UpdateFunc
+// or VersionedUpdateFunc if session was modified by someone else
+// after it had been received.
+var ErrSessionConflict = errors.New("session conflict")
 
 // SessionData describes key:value data
 type SessionData map[string]interface{}
 
+// SessionFactory returns a pointer to a new zero value of user defined
+// session type, e.x. func() interface{} { return &MySession{} }.
+// Received session data is decoded into it with SessionConfig.Decode.
+type SessionFactory func() interface{}
+
 // UpdateFunc describes a func to update session data
 type UpdateFunc func(data []byte) error
 
+// VersionedUpdateFunc describes a func to update session data
+// only if stored session still has the given version.
+// It must return ErrSessionConflict if versions don't match.
+type VersionedUpdateFunc func(data []byte, version string) error
+
+// SessionError is returned by session middleware
+// if session can't be encoded or saved.
+type SessionError struct {
+	// Err is an encode or update error.
+	Err error
+	// HandlerErr is an error returned by the next handler. Optional.
+	HandlerErr error
+}
+
+// Error returns string representation for SessionError
+func (e *SessionError) Error() string {
+	if e.HandlerErr != nil {
+		return fmt.Sprintf(
+			"session save error: %s, handler error: %s",
+			e.Err.Error(), e.HandlerErr.Error())
+	}
+	return fmt.Sprintf("session save error: %s", e.Err.Error())
+}
+
+// IsSessionConflict returns true if err is ErrSessionConflict
+// or SessionError caused by ErrSessionConflict.
+func IsSessionConflict(err error) bool {
+	if sErr, ok := err.(*SessionError); ok {
+		err = sErr.Err
+	}
+	return err == ErrSessionConflict
+}
+
 // SessionConfig helps to configure Session Middleware
 type SessionConfig struct {
 	// Encode takes SessionData and return encoded version
@@ -26,6 +72,18 @@ type SessionConfig struct {
 	// []bytes of current session and UpdateFunc
 	// that is invoked if session is modified
 	GetSession func(context.Context) ([]byte, UpdateFunc, error)
+	// GetVersionedSession is used instead of GetSession if set.
+	// It should return []bytes of current session, version (CAS token)
+	// of this session and VersionedUpdateFunc that is invoked
+	// with the same version if session is modified.
+	GetVersionedSession func(context.Context) (
+		[]byte, string, VersionedUpdateFunc, error)
+	// RetryOnConflict is a number of times the next handler is re-run
+	// with a freshly received session if update returns ErrSessionConflict.
+	// Be aware that handler side effects (e.g. sent messages)
+	// are repeated as well.
+	// Optional, with default value as 0 (no retries).
+	RetryOnConflict int
 }
 
 // GetSession returns SessionData or nil for current context
@@ -41,6 +99,20 @@ func WithSession(ctx context.Context, item SessionData) context.Context {
 	return context.WithValue(ctx, sessionKey{}, item)
 }
 
+// GetTypedSession returns session object created by SessionFactory
+// or nil for current context.
+// Use type assertion to your session type, e.x.
+//
+//	session := telebot.GetTypedSession(ctx).(*MySession)
+func GetTypedSession(ctx context.Context) interface{} {
+	return ctx.Value(typedSessionKey{})
+}
+
+// WithTypedSession returns a new context with session object inside.
+func WithTypedSession(ctx context.Context, item interface{}) context.Context {
+	return context.WithValue(ctx, typedSessionKey{}, item)
+}
+
 // Session is a default middleware to work with sessions.
 // getSession function should receive request context and return
 // []bytes of current session, UpdateFunc that is invoked if session is modified
@@ -51,8 +123,55 @@ func Session(getSession func(context.Context) ([]byte, UpdateFunc, error)) Middl
 	})
 }
 
-// SessionWithConfig takes SessionConfig and returns SessionMiddleware
+// SessionWithConfig takes SessionConfig and returns SessionMiddleware.
+// Use GetSession to take SessionData from context.
+//
+// Session is saved after the next handler returns, even if it returns
+// an error or panics. Encode and update errors are returned as SessionError,
+// so they reach Bot.ErrorFunc, they are lost if handler panics.
 func SessionWithConfig(cfg SessionConfig) MiddlewareFunc {
+	if encode := cfg.Encode; encode != nil {
+		// Encode receives SessionData, not a pointer to it
+		cfg.Encode = func(item interface{}) ([]byte, error) {
+			return encode(*item.(*SessionData))
+		}
+	}
+	return sessionMiddleware(
+		cfg,
+		func() interface{} { return &SessionData{} },
+		func(ctx context.Context, item interface{}) context.Context {
+			return WithSession(ctx, *item.(*SessionData))
+		},
+	)
+}
+
+// TypedSession is like Session, but decodes session into
+// an object created by factory instead of SessionData.
+// Use GetTypedSession to take session object from context.
+func TypedSession(
+	factory SessionFactory,
+	getSession func(context.Context) ([]byte, UpdateFunc, error)) MiddlewareFunc {
+
+	return TypedSessionWithConfig(factory, SessionConfig{
+		GetSession: getSession,
+	})
+}
+
+// TypedSessionWithConfig takes SessionFactory and SessionConfig
+// and returns SessionMiddleware that works with user defined session type.
+// Use GetTypedSession to take session object from context.
+func TypedSessionWithConfig(factory SessionFactory, cfg SessionConfig) MiddlewareFunc {
+	return sessionMiddleware(cfg, factory, WithTypedSession)
+}
+
+// ============== Internal ================================================== //
+
+func sessionMiddleware(
+	cfg SessionConfig,
+	factory SessionFactory,
+	withSession func(context.Context, interface{}) context.Context,
+) MiddlewareFunc {
+
 	encode := cfg.Encode
 	if encode == nil {
 		encode = json.Marshal
@@ -61,36 +180,79 @@ func SessionWithConfig(cfg SessionConfig) MiddlewareFunc {
 	if decode == nil {
 		decode = json.Unmarshal
 	}
+	getSession := cfg.GetVersionedSession
+	if getSession == nil {
+		getSession = unversionedSession(cfg.GetSession)
+	}
+	s := &sessionHandler{
+		getSession:  getSession,
+		encode:      encode,
+		decode:      decode,
+		factory:     factory,
+		withSession: withSession,
+	}
 
 	return func(next Handler) Handler {
 		return HandlerFunc(func(ctx context.Context) error {
-			sessionBytes, update, err := cfg.GetSession(ctx)
-			if err != nil {
-				return err
-			}
-			sessionData := SessionData{}
-			if sessionBytes != nil && len(sessionBytes) > 0 {
-				err = decode(sessionBytes, &sessionData)
-				if err != nil {
-					return err
+			for attempt := 0; ; attempt++ {
+				err := s.handle(ctx, next)
+				if IsSessionConflict(err) &&
+					attempt < cfg.RetryOnConflict {
+					continue
 				}
+				return err
 			}
+		})
+	}
+}
 
-			ctx = WithSession(ctx, sessionData)
-			defer func() {
-				data, err := encode(sessionData)
-				if err != nil {
-					return
-				}
-				if !bytes.Equal(data, sessionBytes) {
-					err := update(data)
-					if err != nil {
-						return
-					}
-				}
-			}()
-			err = next.Handle(ctx)
+type sessionHandler struct {
+	getSession  func(context.Context) ([]byte, string, VersionedUpdateFunc, error)
+	encode      func(interface{}) ([]byte, error)
+	decode      func(data []byte, dst interface{}) error
+	factory     SessionFactory
+	withSession func(context.Context, interface{}) context.Context
+}
+
+func unversionedSession(
+	getSession func(context.Context) ([]byte, UpdateFunc, error),
+) func(context.Context) ([]byte, string, VersionedUpdateFunc, error) {
+
+	return func(ctx context.Context) (
+		[]byte, string, VersionedUpdateFunc, error) {
+
+		data, update, err := getSession(ctx)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		return data, "", func(data []byte, _ string) error {
+			return update(data)
+		}, nil
+	}
+}
+
+func (s *sessionHandler) handle(ctx context.Context, next Handler) (err error) {
+	sessionBytes, version, update, err := s.getSession(ctx)
+	if err != nil {
+		return err
+	}
+	session := s.factory()
+	if len(sessionBytes) > 0 {
+		err = s.decode(sessionBytes, session)
+		if err != nil {
 			return err
-		})
+		}
 	}
+
+	// session is saved in defer, so changes aren't lost if handler panics
+	defer func() {
+		data, sErr := s.encode(session)
+		if sErr == nil && !bytes.Equal(data, sessionBytes) {
+			sErr = update(data, version)
+		}
+		if sErr != nil {
+			err = &SessionError{Err: sErr, HandlerErr: err}
+		}
+	}()
+	return next.Handle(s.withSession(ctx, session))
 }
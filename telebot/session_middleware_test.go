@@ -0,0 +1,239 @@
+package telebot_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/bot-api/telegram/telebot"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+// versionedStore is a simple CAS storage for a single session
+type versionedStore struct {
+	data    []byte
+	version int
+	// modify is invoked before update to simulate concurrent writes
+	modify func(s *versionedStore)
+}
+
+func (s *versionedStore) get(context.Context) (
+	[]byte, string, telebot.VersionedUpdateFunc, error) {
+
+	return s.data, strconv.Itoa(s.version),
+		func(data []byte, version string) error {
+			if s.modify != nil {
+				s.modify(s)
+			}
+			if version != strconv.Itoa(s.version) {
+				return telebot.ErrSessionConflict
+			}
+			s.data = data
+			s.version++
+			return nil
+		}, nil
+}
+
+func TestSession(t *testing.T) {
+	var saved []byte
+	m := telebot.Session(func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+		return []byte(`{"counter":1}`), func(data []byte) error {
+			saved = data
+			return nil
+		}, nil
+	})
+	h := m(telebot.HandlerFunc(func(ctx context.Context) error {
+		session := telebot.GetSession(ctx)
+		require.NotNil(t, session)
+		assert.Equal(t, float64(1), session["counter"])
+		session["counter"] = 2
+		return nil
+	}))
+	require.NoError(t, h.Handle(context.Background()))
+	assert.Equal(t, `{"counter":2}`, string(saved))
+
+	assert.Nil(t, telebot.GetSession(context.Background()))
+
+	// session is saved if handler panics
+	h = m(telebot.HandlerFunc(func(ctx context.Context) error {
+		telebot.GetSession(ctx)["counter"] = 3
+		panic("handler panic")
+	}))
+	assert.Panics(t, func() { h.Handle(context.Background()) })
+	assert.Equal(t, `{"counter":3}`, string(saved))
+}
+
+func TestSessionWithConfig_encode(t *testing.T) {
+	var saved []byte
+	m := telebot.SessionWithConfig(telebot.SessionConfig{
+		Encode: func(item interface{}) ([]byte, error) {
+			session, ok := item.(telebot.SessionData)
+			require.True(t, ok)
+			return []byte(fmt.Sprintf("counter=%v", session["counter"])), nil
+		},
+		GetSession: func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+			return nil, func(data []byte) error {
+				saved = data
+				return nil
+			}, nil
+		},
+	})
+	h := m(telebot.HandlerFunc(func(ctx context.Context) error {
+		telebot.GetSession(ctx)["counter"] = 1
+		return nil
+	}))
+	require.NoError(t, h.Handle(context.Background()))
+	assert.Equal(t, "counter=1", string(saved))
+}
+
+func TestSessionWithConfig_errors(t *testing.T) {
+	updErr := fmt.Errorf("update error")
+	hErr := fmt.Errorf("handler error")
+
+	m := telebot.Session(func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+		return nil, func(data []byte) error {
+			return updErr
+		}, nil
+	})
+	{
+		err := m(telebot.EmptyHandler()).Handle(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, &telebot.SessionError{Err: updErr}, err)
+		assert.Equal(t, "session save error: update error", err.Error())
+	}
+	{
+		err := m(telebot.HandlerFunc(func(context.Context) error {
+			return hErr
+		})).Handle(context.Background())
+		assert.Equal(t, &telebot.SessionError{
+			Err:        updErr,
+			HandlerErr: hErr,
+		}, err)
+	}
+
+	encErr := fmt.Errorf("encode error")
+	m = telebot.SessionWithConfig(telebot.SessionConfig{
+		Encode: func(interface{}) ([]byte, error) {
+			return nil, encErr
+		},
+		GetSession: func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+			return nil, func(data []byte) error {
+				t.Error("update shouldn't be invoked")
+				return nil
+			}, nil
+		},
+	})
+	{
+		err := m(telebot.EmptyHandler()).Handle(context.Background())
+		assert.Equal(t, &telebot.SessionError{Err: encErr}, err)
+	}
+
+	getErr := fmt.Errorf("get error")
+	m = telebot.Session(func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+		return nil, nil, getErr
+	})
+	{
+		err := m(telebot.HandlerFunc(func(context.Context) error {
+			t.Error("handler shouldn't be invoked")
+			return nil
+		})).Handle(context.Background())
+		assert.Equal(t, getErr, err)
+	}
+}
+
+func TestSessionWithConfig_conflict(t *testing.T) {
+	store := &versionedStore{data: []byte(`{"counter":1}`)}
+	// every update is preceded by a concurrent one
+	store.modify = func(s *versionedStore) {
+		s.version++
+	}
+
+	invoked := 0
+	h := telebot.HandlerFunc(func(ctx context.Context) error {
+		invoked++
+		telebot.GetSession(ctx)["counter"] = invoked * 10
+		return nil
+	})
+
+	m := telebot.SessionWithConfig(telebot.SessionConfig{
+		GetVersionedSession: store.get,
+	})
+	err := m(h).Handle(context.Background())
+	assert.True(t, telebot.IsSessionConflict(err))
+	assert.Equal(t, 1, invoked)
+	assert.Equal(t, `{"counter":1}`, string(store.data))
+
+	// only the first update has a concurrent one
+	invoked = 0
+	store.modify = func(s *versionedStore) {
+		s.version++
+		s.modify = nil
+	}
+	m = telebot.SessionWithConfig(telebot.SessionConfig{
+		GetVersionedSession: store.get,
+		RetryOnConflict:     2,
+	})
+	err = m(h).Handle(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, invoked)
+	assert.Equal(t, `{"counter":20}`, string(store.data))
+
+	assert.False(t, telebot.IsSessionConflict(fmt.Errorf("error")))
+	assert.True(t, telebot.IsSessionConflict(telebot.ErrSessionConflict))
+}
+
+type typedSession struct {
+	Counter int64     `json:"counter"`
+	Items   []string  `json:"items"`
+	Profile *struct{} `json:"profile"`
+}
+
+func TestTypedSession(t *testing.T) {
+	var saved []byte
+	m := telebot.TypedSession(
+		func() interface{} { return &typedSession{} },
+		func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+			return []byte(`{"counter":9007199254740993,"items":["a"]}`),
+				func(data []byte) error {
+					saved = data
+					return nil
+				}, nil
+		})
+	h := m(telebot.HandlerFunc(func(ctx context.Context) error {
+		session, ok := telebot.GetTypedSession(ctx).(*typedSession)
+		require.True(t, ok)
+		// int64 values don't lose precision
+		assert.Equal(t, int64(9007199254740993), session.Counter)
+		assert.Equal(t, []string{"a"}, session.Items)
+		session.Counter++
+		session.Items = append(session.Items, "b")
+		// map based session isn't set
+		assert.Nil(t, telebot.GetSession(ctx))
+		return nil
+	}))
+	require.NoError(t, h.Handle(context.Background()))
+	assert.Equal(t,
+		`{"counter":9007199254740994,"items":["a","b"],"profile":null}`,
+		string(saved))
+
+	assert.Nil(t, telebot.GetTypedSession(context.Background()))
+}
+
+func TestTypedSessionWithConfig(t *testing.T) {
+	decErr := fmt.Errorf("decode error")
+	m := telebot.TypedSessionWithConfig(
+		func() interface{} { return &typedSession{} },
+		telebot.SessionConfig{
+			Decode: func(data []byte, dst interface{}) error {
+				assert.IsType(t, &typedSession{}, dst)
+				return decErr
+			},
+			GetSession: func(context.Context) ([]byte, telebot.UpdateFunc, error) {
+				return []byte(`{}`), nil, nil
+			},
+		})
+	err := m(telebot.EmptyHandler()).Handle(context.Background())
+	assert.Equal(t, decErr, err)
+}
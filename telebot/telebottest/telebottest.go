@@ -0,0 +1,360 @@
+// Package telebottest helps to write scenario tests for telebot bots.
+//
+// A Tester drives a real telebot.Bot with its middleware stack
+// against a fake Bot API server (testutils.Server).
+// Every user action is handled synchronously, so expectations
+// can be checked right after it without any channel plumbing:
+//
+//	tt := telebottest.New(t, func(api *telegram.API) *telebot.Bot {
+//		return NewMyBot(api)
+//	})
+//	defer tt.Close()
+//
+//	user := tt.User(42)
+//	user.Sends("/start")
+//	user.ExpectMessage(telebottest.Contains("Welcome"),
+//		telebottest.HasButton("Like"))
+//	user.Presses("Like")
+//	user.ExpectAnswer("Thanks!")
+//	user.ExpectNoMessages()
+//
+// Handlers should use telebot.Now to take current time,
+// that is controlled by Tester.Clock.
+package telebottest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/testutils"
+	"golang.org/x/net/context"
+)
+
+// Clock is a deterministic clock that changes only by Advance.
+// It implements telebot.TimerClock, so timers of middleware
+// fire only when the clock is advanced.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*clockTimer
+}
+
+// NewClock returns a clock that starts at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d. Functions of timers
+// that become due are called synchronously in order of their time,
+// the clock shows the time of a timer while its function runs.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+	for t := c.popTimer(end); t != nil; t = c.popTimer(end) {
+		if t.at.After(c.now) {
+			c.now = t.at
+		}
+		c.mu.Unlock()
+		t.f()
+		c.mu.Lock()
+	}
+	if end.After(c.now) {
+		c.now = end
+	}
+}
+
+// AfterFunc calls f when the clock is advanced by d.
+// It implements telebot.TimerClock.
+func (c *Clock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &clockTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i := range c.timers {
+			if c.timers[i] == t {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+type clockTimer struct {
+	at time.Time
+	f  func()
+}
+
+// popTimer removes and returns the earliest timer
+// that is due at end or nil, c.mu must be held.
+func (c *Clock) popTimer(end time.Time) *clockTimer {
+	next := -1
+	for i, t := range c.timers {
+		if !t.at.After(end) && (next < 0 || t.at.Before(c.timers[next].at)) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil
+	}
+	t := c.timers[next]
+	c.timers = append(c.timers[:next], c.timers[next+1:]...)
+	return t
+}
+
+// A Tester runs scenario steps against a bot.
+type Tester struct {
+	// Server is a fake Bot API server used by bot.
+	Server *testutils.Server
+	// Bot is a bot under test.
+	Bot *telebot.Bot
+	// Clock is passed to every update context.
+	// It starts at 2016-01-01 00:00:00 UTC.
+	Clock *Clock
+
+	t      testing.TB
+	api    *telegram.API
+	offset int64
+}
+
+// New starts a fake server and makes a bot by newBot
+// with API client for this server. Tester should be closed.
+func New(t testing.TB, newBot func(api *telegram.API) *telebot.Bot) *Tester {
+	srv := testutils.NewServer("token")
+	clock := NewClock(time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+	srv.Now = clock.Now
+	return &Tester{
+		Server: srv,
+		Bot:    newBot(srv.API()),
+		Clock:  clock,
+		t:      t,
+		api:    srv.API(),
+	}
+}
+
+// Close stops fake server.
+func (tt *Tester) Close() {
+	tt.Server.Close()
+}
+
+// User returns a user with id, that talks to bot in a private chat
+// with the same id.
+func (tt *Tester) User(id int64) *User {
+	return &User{
+		User: telegram.User{
+			ID:        id,
+			FirstName: fmt.Sprintf("User%d", id),
+		},
+		ChatID: id,
+		tt:     tt,
+	}
+}
+
+// Run handles all pending updates synchronously.
+// User actions invoke it, so it's needed only
+// for updates added to Server directly.
+func (tt *Tester) Run() {
+	ctx := telebot.WithClock(context.Background(), tt.Clock)
+	for {
+		updates, err := tt.api.GetUpdates(ctx, telegram.UpdateCfg{
+			Offset: tt.offset,
+		})
+		if err != nil {
+			tt.t.Fatalf("getUpdates error: %s", err.Error())
+			return
+		}
+		if len(updates) == 0 {
+			return
+		}
+		for i := range updates {
+			tt.offset = updates[i].UpdateID + 1
+			tt.Bot.HandleUpdate(ctx, &updates[i])
+		}
+	}
+}
+
+// A User acts in a chat with bot and checks bot messages.
+type User struct {
+	telegram.User
+	// ChatID is a chat where user sends messages.
+	ChatID int64
+
+	tt *Tester
+	// seen is a number of bot messages that were checked.
+	seen int
+	// query is the last callback query sent by user.
+	query *telegram.CallbackQuery
+}
+
+// InChat returns the same user, that acts in another chat.
+// Chat is added to server if it doesn't exist yet.
+func (u *User) InChat(chat telegram.Chat) *User {
+	u.tt.Server.AddChat(chat)
+	return &User{
+		User:   u.User,
+		ChatID: chat.ID,
+		tt:     u.tt,
+	}
+}
+
+// Sends sends text message to bot and waits until it's handled.
+func (u *User) Sends(text string) telegram.Message {
+	msg := u.tt.Server.UserMessage(u.ChatID, u.User, text)
+	u.tt.Run()
+	return msg
+}
+
+// Presses presses inline button with text under the latest bot message
+// that has such a button, and waits until callback query is handled.
+func (u *User) Presses(button string) {
+	msgs := u.tt.Server.BotMessages(u.ChatID)
+	for i := len(msgs) - 1; i >= 0; i-- {
+		for _, row := range u.tt.Server.InlineKeyboard(msgs[i]) {
+			for _, b := range row {
+				if b.Text == button {
+					query := u.tt.Server.PressButton(msgs[i], u.User, b.CallbackData)
+					u.query = &query
+					u.tt.Run()
+					return
+				}
+			}
+		}
+	}
+	u.tt.t.Fatalf("button %q isn't found in chat %d", button, u.ChatID)
+}
+
+// ExpectMessage checks the next bot message in chat with matchers
+// and returns it.
+func (u *User) ExpectMessage(matchers ...Matcher) telegram.Message {
+	msgs := u.tt.Server.BotMessages(u.ChatID)
+	if u.seen >= len(msgs) {
+		u.tt.t.Fatalf("expected a message in chat %d, got nothing", u.ChatID)
+		return telegram.Message{}
+	}
+	msg := msgs[u.seen]
+	u.seen++
+	u.check(msg, matchers)
+	return msg
+}
+
+// ExpectEdited checks that message under the latest pressed button
+// was edited and matches matchers.
+func (u *User) ExpectEdited(matchers ...Matcher) telegram.Message {
+	if u.query == nil {
+		u.tt.t.Fatalf("no button was pressed in chat %d", u.ChatID)
+		return telegram.Message{}
+	}
+	for _, msg := range u.tt.Server.Messages(u.ChatID) {
+		if msg.MessageID != u.query.Message.MessageID {
+			continue
+		}
+		if msg.EditDate == 0 {
+			u.tt.t.Errorf("message %d isn't edited", msg.MessageID)
+		}
+		u.check(msg, matchers)
+		return msg
+	}
+	u.tt.t.Fatalf("message %d isn't found", u.query.Message.MessageID)
+	return telegram.Message{}
+}
+
+// ExpectNoMessages checks that bot hasn't sent unchecked messages.
+func (u *User) ExpectNoMessages() {
+	msgs := u.tt.Server.BotMessages(u.ChatID)
+	for _, msg := range msgs[u.seen:] {
+		u.tt.t.Errorf("unexpected message in chat %d: %q",
+			u.ChatID, msg.Text)
+	}
+	u.seen = len(msgs)
+}
+
+// ExpectAnswer checks that the latest callback query was answered
+// with text.
+func (u *User) ExpectAnswer(text string) {
+	if u.query == nil {
+		u.tt.t.Fatalf("no button was pressed in chat %d", u.ChatID)
+		return
+	}
+	answer, ok := u.tt.Server.CallbackAnswer(u.query.ID)
+	if !ok {
+		u.tt.t.Errorf("callback query %q isn't answered", u.query.Data)
+		return
+	}
+	if answer.Text != text {
+		u.tt.t.Errorf("expected answer %q, got %q", text, answer.Text)
+	}
+}
+
+func (u *User) check(msg telegram.Message, matchers []Matcher) {
+	keyboard := u.tt.Server.InlineKeyboard(msg)
+	for _, m := range matchers {
+		if err := m(msg, keyboard); err != nil {
+			u.tt.t.Errorf("message %d in chat %d: %s",
+				msg.MessageID, u.ChatID, err.Error())
+		}
+	}
+}
+
+// Matcher checks bot message and its inline keyboard.
+type Matcher func(msg telegram.Message, keyboard [][]telegram.InlineKeyboardButton) error
+
+// Text matches message with exactly the same text.
+func Text(text string) Matcher {
+	return func(msg telegram.Message, _ [][]telegram.InlineKeyboardButton) error {
+		if msg.Text != text {
+			return fmt.Errorf("expected text %q, got %q", text, msg.Text)
+		}
+		return nil
+	}
+}
+
+// Contains matches message which text contains substr.
+func Contains(substr string) Matcher {
+	return func(msg telegram.Message, _ [][]telegram.InlineKeyboardButton) error {
+		if !strings.Contains(msg.Text, substr) {
+			return fmt.Errorf("expected text containing %q, got %q",
+				substr, msg.Text)
+		}
+		return nil
+	}
+}
+
+// HasButton matches message with inline button with text.
+func HasButton(text string) Matcher {
+	return func(_ telegram.Message, keyboard [][]telegram.InlineKeyboardButton) error {
+		for _, row := range keyboard {
+			for _, b := range row {
+				if b.Text == text {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("expected inline button %q", text)
+	}
+}
+
+// NoKeyboard matches message without inline keyboard.
+func NoKeyboard() Matcher {
+	return func(_ telegram.Message, keyboard [][]telegram.InlineKeyboardButton) error {
+		if len(keyboard) > 0 {
+			return fmt.Errorf("expected no inline keyboard, got %v", keyboard)
+		}
+		return nil
+	}
+}
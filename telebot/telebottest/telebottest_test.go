@@ -0,0 +1,153 @@
+package telebottest_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/telebot/telebottest"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func newLikeBot(api *telegram.API) *telebot.Bot {
+	b := telebot.NewWithAPI(api)
+	b.Use(telebot.Commands(map[string]telebot.Commander{
+		"start": telebot.CommandFunc(
+			func(ctx context.Context, arg string) error {
+				msg := telebot.GetUpdate(ctx).Message
+				cfg := telegram.NewMessage(msg.Chat.ID, fmt.Sprintf(
+					"Welcome, %s! It's %s",
+					msg.From.FirstName,
+					telebot.Now(ctx).Format("15:04")))
+				cfg.ReplyMarkup = telegram.InlineKeyboardMarkup{
+					InlineKeyboard: telegram.NewVInlineKeyboard(
+						"like:",
+						[]string{"Like", "Dislike"},
+						[]string{"1", "0"}),
+				}
+				_, err := telebot.GetAPI(ctx).SendMessage(ctx, cfg)
+				return err
+			}),
+	}))
+	b.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+		"like": telebot.CallbackFunc(
+			func(ctx context.Context, data string) error {
+				query := telebot.GetUpdate(ctx).CallbackQuery
+				api := telebot.GetAPI(ctx)
+				_, err := api.EditMessageText(ctx, telegram.NewEditMessageText(
+					query.Message.Chat.ID,
+					query.Message.MessageID,
+					"Voted "+data))
+				if err != nil {
+					return err
+				}
+				_, err = api.AnswerCallbackQuery(ctx,
+					telegram.NewAnswerCallback(query.ID, "Thanks!"))
+				return err
+			}),
+	}))
+	return b
+}
+
+func TestTester(t *testing.T) {
+	tt := telebottest.New(t, newLikeBot)
+	defer tt.Close()
+	tt.Clock.Advance(time.Hour + time.Minute*30)
+
+	user := tt.User(42)
+	user.Sends("/start")
+	msg := user.ExpectMessage(
+		telebottest.Text("Welcome, User42! It's 01:30"),
+		telebottest.HasButton("Like"),
+		telebottest.HasButton("Dislike"),
+	)
+	assert.Equal(t, tt.Clock.Now().Unix(), int64(msg.Date))
+	user.ExpectNoMessages()
+
+	user.Presses("Like")
+	user.ExpectAnswer("Thanks!")
+	user.ExpectEdited(telebottest.Contains("Voted 1"), telebottest.NoKeyboard())
+	user.ExpectNoMessages()
+
+	// another user in a group chat
+	group := tt.User(43).InChat(telegram.Chat{
+		ID:   -1,
+		Type: telegram.GroupChatType,
+	})
+	group.Sends("hello")
+	group.ExpectNoMessages()
+	user.ExpectNoMessages()
+}
+
+// recorder catches test failures
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestTester_failures(t *testing.T) {
+	rec := &recorder{}
+	tt := telebottest.New(rec, newLikeBot)
+	defer tt.Close()
+
+	user := tt.User(42)
+	user.ExpectAnswer("Thanks!")
+	user.Presses("Like")
+	user.ExpectMessage()
+	user.Sends("/start")
+	user.ExpectMessage(telebottest.Contains("Bye"), telebottest.HasButton("Share"))
+	user.Sends("/start")
+	user.ExpectNoMessages()
+
+	assert.Equal(t, []string{
+		"no button was pressed in chat 42",
+		`button "Like" isn't found in chat 42`,
+		"expected a message in chat 42, got nothing",
+		`message 3 in chat 42: expected text containing "Bye", ` +
+			`got "Welcome, User42! It's 00:00"`,
+		`message 3 in chat 42: expected inline button "Share"`,
+		`unexpected message in chat 42: "Welcome, User42! It's 00:00"`,
+	}, rec.errors)
+}
+
+func TestClock_AfterFunc(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := telebottest.NewClock(start)
+	var fired []time.Duration
+	after := func(d time.Duration) func() bool {
+		return clock.AfterFunc(d, func() {
+			fired = append(fired, clock.Now().Sub(start))
+		})
+	}
+	after(time.Second * 2)
+	after(time.Second)
+	stop := after(time.Second * 3)
+	after(time.Second * 5)
+
+	clock.Advance(time.Millisecond * 999)
+	assert.Empty(t, fired)
+	assert.True(t, stop())
+	assert.False(t, stop())
+	clock.Advance(time.Second * 3)
+	assert.Equal(t, []time.Duration{time.Second, time.Second * 2}, fired)
+	assert.Equal(t, time.Millisecond*3999, clock.Now().Sub(start))
+
+	// timers set by timer functions fire in the same advance
+	clock.AfterFunc(0, func() { after(time.Millisecond) })
+	clock.Advance(time.Millisecond * 1001)
+	assert.Equal(t, []time.Duration{
+		time.Second, time.Second * 2,
+		time.Millisecond * 4000, time.Second * 5,
+	}, fired)
+}
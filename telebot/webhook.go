@@ -0,0 +1,612 @@
+package telebot
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// SecretTokenHeader is a header that contains
+// telegram.WebhookCfg.SecretToken in every webhook request.
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+type (
+	// WebhookHandlerCfg defines the config for webhook handler.
+	WebhookHandlerCfg struct {
+		// SecretToken is compared with SecretTokenHeader
+		// of every request. Use the same value as
+		// telegram.WebhookCfg.SecretToken.
+		// Optional, header isn't checked if empty.
+		SecretToken string
+
+		// AllowedNetworks restricts addresses of webhook requests.
+		// Use TelegramNetworks to accept requests from Telegram only.
+		// Optional, all addresses are allowed if empty.
+		AllowedNetworks []*net.IPNet
+
+		// RealIPHeader is a header that contains client address,
+		// e.x. X-Real-IP or X-Forwarded-For if bot is behind a proxy.
+		// The last address of the header is used, it's added by
+		// the proxy, addresses before it are sent by client
+		// and can be spoofed.
+		// Optional, request RemoteAddr is used if empty.
+		RealIPHeader string
+
+		// MaxBodySize is a maximum size of request body in bytes.
+		// Optional, with default value as 1 MB.
+		MaxBodySize int64
+
+		// ReplyTimeout is a maximum time to wait for handler
+		// to set a method by WebhookReply. Method is sent back
+		// in response body as soon as handler sets it.
+		// Update continues to be handled asynchronously
+		// after timeout, WebhookReply invokes methods by API then.
+		// Telegram doesn't send more updates until it gets a response,
+		// so keep it short. Optional, webhook replies are disabled
+		// and response is sent immediately if zero.
+		ReplyTimeout time.Duration
+
+		// DedupeWindow is a number of the latest update ids
+		// remembered to skip updates that Telegram redelivers
+		// if bot responds slowly. Duplicates are answered with 200.
+		// Optional, deduplication is disabled if zero.
+		DedupeWindow int
+
+		// Journal stores received updates until they are handled.
+		// Pending updates are replayed on start before new ones,
+		// and are answered with 500 if they can't be stored,
+		// so Telegram retries them later. Optional.
+		Journal UpdateJournal
+	}
+
+	// WebhookServerCfg defines the config for Bot.ServeWebhook.
+	WebhookServerCfg struct {
+		// Addr is a TCP address to listen on, e.x. ":8443".
+		Addr string
+
+		// Listener is used instead of Addr if set. Optional.
+		Listener net.Listener
+
+		// CertFile and KeyFile are paths to TLS certificate and key.
+		// Server uses plain HTTP if they are empty,
+		// e.x. if bot is behind a TLS terminating proxy. Optional.
+		CertFile string
+		KeyFile  string
+
+		// Webhook is registered by SetWebhook on start.
+		// Handler serves requests on a path of Webhook.URL.
+		Webhook telegram.WebhookCfg
+
+		// Handler configures webhook handler.
+		// Handler.SecretToken defaults to Webhook.SecretToken.
+		// Use DefaultWebhookHandlerConfig as a base.
+		Handler WebhookHandlerCfg
+
+		// DropPendingUpdates drops pending updates
+		// when webhook is deleted on shutdown. Optional.
+		DropPendingUpdates bool
+
+		// ShutdownTimeout is a maximum time to wait for active
+		// requests on shutdown, connections are closed after it.
+		// Requests are waited only if bot is built with go 1.8+.
+		// Optional, with default value as 5 seconds.
+		ShutdownTimeout time.Duration
+	}
+)
+
+var (
+	// DefaultWebhookHandlerConfig is the default webhook handler config.
+	DefaultWebhookHandlerConfig = WebhookHandlerCfg{
+		MaxBodySize:  1 << 20, // 1 MB
+		DedupeWindow: 1000,
+	}
+
+	// TelegramNetworks contains subnets that Telegram uses
+	// to send webhook requests.
+	TelegramNetworks = []*net.IPNet{
+		mustParseCIDR("149.154.160.0/20"),
+		mustParseCIDR("91.108.4.0/22"),
+	}
+)
+
+// ServeByWebhook returns webhook handler,
+// that can handle incoming telegram webhook messages.
+// It uses DefaultWebhookHandlerConfig.
+//
+// Use IsWebhook function to identify webhook updates.
+func (b *Bot) ServeByWebhook(ctx context.Context) (http.HandlerFunc, error) {
+	return b.ServeByWebhookWithConfig(ctx, DefaultWebhookHandlerConfig)
+}
+
+// WebhookReply sets method m as a reply to a webhook update.
+// The method is serialized into webhook response body,
+// that saves a request to Telegram API. Result of the method is unknown.
+//
+// Method is invoked by API if update isn't received by webhook,
+// webhook replies are disabled or reply timeout has passed,
+// the method uploads a file or another method has been already set.
+func WebhookReply(ctx context.Context, m telegram.Method) error {
+	if reply, ok := ctx.Value(webhookReplyKey{}).(*webhookReply); ok {
+		set, err := reply.set(m)
+		if set || err != nil {
+			return err
+		}
+	}
+	return GetAPI(ctx).Invoke(ctx, m, nil)
+}
+
+// ServeByWebhookWithConfig returns webhook handler configured by cfg.
+//
+// Handler responds with:
+//   - 405 if request method isn't POST;
+//   - 403 if request address isn't in AllowedNetworks;
+//   - 401 if SecretTokenHeader doesn't match SecretToken;
+//   - 413 if request body is larger than MaxBodySize;
+//   - 400 if request body isn't a valid update;
+//   - 500 if update can't be stored in Journal;
+//   - 503 if bot is stopped and update can't be handled,
+//     so Telegram retries it later;
+//   - 200 with a method set by WebhookReply or empty body otherwise.
+func (b *Bot) ServeByWebhookWithConfig(
+	ctx context.Context,
+	cfg WebhookHandlerCfg) (http.HandlerFunc, error) {
+
+	if err := b.updateMe(ctx); err != nil {
+		return nil, err
+	}
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = DefaultWebhookHandlerConfig.MaxBodySize
+	}
+	var pending []telegram.Update
+	if cfg.Journal != nil {
+		var err error
+		if pending, err = cfg.Journal.Pending(ctx); err != nil {
+			return nil, err
+		}
+	}
+	dedupe := newDedupeWindow(cfg.DedupeWindow)
+	for _, update := range pending {
+		dedupe.seen(update.UpdateID)
+	}
+
+	updatesCh := make(chan webhookUpdate)
+	go func() {
+		for i := range pending {
+			if ctx.Err() != nil {
+				return
+			}
+			b.handleWebhookUpdate(ctx, cfg, webhookUpdate{
+				update: pending[i],
+			})
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case wu := <-updatesCh:
+				b.handleWebhookUpdate(ctx, cfg, wu)
+			}
+		}
+	}()
+	return b.getWebhookHandler(ctx, cfg, dedupe, updatesCh), nil
+}
+
+// ServeWebhook runs http server that handles webhook updates,
+// registers webhook by SetWebhook and deletes it by DeleteWebhook
+// when ctx is done or server fails. Server is shut down gracefully,
+// see ShutdownTimeout.
+//
+// It returns server error if server fails, otherwise
+// DeleteWebhook error if webhook can't be deleted or ctx.Err().
+func (b *Bot) ServeWebhook(ctx context.Context, cfg WebhookServerCfg) error {
+	if cfg.Handler.SecretToken == "" {
+		cfg.Handler.SecretToken = cfg.Webhook.SecretToken
+	}
+	u, err := url.Parse(cfg.Webhook.URL)
+	if err != nil {
+		return err
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	handler, err := b.ServeByWebhookWithConfig(ctx, cfg.Handler)
+	if err != nil {
+		return err
+	}
+
+	ln, err := webhookListener(cfg)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	srv := newWebhookServer(ln, mux)
+	srvErr := make(chan error, 1)
+	go func() {
+		srvErr <- srv.serve()
+	}()
+
+	if err = b.api.SetWebhook(ctx, cfg.Webhook); err != nil {
+		srv.shutdown(0)
+		return err
+	}
+
+	select {
+	case err = <-srvErr:
+	case <-ctx.Done():
+	}
+	timeout := cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = time.Second * 5
+	}
+	srv.shutdown(timeout)
+
+	// ctx is probably done, so use a new one to delete webhook
+	delCtx, delCancel := context.WithTimeout(
+		context.Background(), time.Second*10)
+	defer delCancel()
+	delErr := b.api.DeleteWebhook(delCtx, telegram.DeleteWebhookCfg{
+		DropPendingUpdates: cfg.DropPendingUpdates,
+	})
+	switch {
+	case err != nil:
+		return err
+	case delErr != nil:
+		return delErr
+	}
+	return ctx.Err()
+}
+
+// ============== Internal ================================================== //
+
+func webhookListener(cfg WebhookServerCfg) (net.Listener, error) {
+	ln := cfg.Listener
+	if ln == nil {
+		var err error
+		if ln, err = net.Listen("tcp", cfg.Addr); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		return ln, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return tls.NewListener(ln, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}), nil
+}
+
+type webhookReplyKey struct{}
+
+type webhookUpdate struct {
+	update telegram.Update
+	reply  *webhookReply
+}
+
+// webhookReply is a slot for a method that is sent in webhook response.
+// done is closed when method is set or update handling is finished.
+type webhookReply struct {
+	mu       sync.Mutex
+	data     []byte
+	closed   bool
+	signaled bool
+	done     chan struct{}
+}
+
+func newWebhookReply() *webhookReply {
+	return &webhookReply{done: make(chan struct{})}
+}
+
+// set encodes method and puts it to the reply.
+// It returns false if reply is closed or already has a method.
+func (r *webhookReply) set(m telegram.Method) (bool, error) {
+	if f, ok := m.(telegram.Filer); ok && !f.Exist() {
+		// files can't be uploaded in response
+		return false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || r.data != nil {
+		return false, nil
+	}
+	data, err := encodeWebhookReply(m)
+	if err != nil {
+		return false, err
+	}
+	r.data = data
+	r.signal()
+	return true, nil
+}
+
+// close closes reply and returns data of the method if it's set.
+func (r *webhookReply) close() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return r.data
+}
+
+// finish tells that update handling is finished.
+func (r *webhookReply) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.signal()
+}
+
+// signal closes done once, r.mu must be held.
+func (r *webhookReply) signal() {
+	if !r.signaled {
+		r.signaled = true
+		close(r.done)
+	}
+}
+
+// jsonParams are method params that are encoded to json by Values.
+// They are sent as json values in webhook reply, not as strings.
+var jsonParams = map[string]bool{
+	"reply_markup":     true,
+	"results":          true,
+	"entities":         true,
+	"caption_entities": true,
+	"allowed_updates":  true,
+}
+
+// encodeWebhookReply returns json object with method params
+// and method name in "method" field.
+func encodeWebhookReply(m telegram.Method) ([]byte, error) {
+	values, err := m.Values()
+	if err != nil {
+		return nil, err
+	}
+	obj := make(map[string]interface{}, len(values)+1)
+	for key, value := range values {
+		var raw json.RawMessage
+		switch {
+		case len(value) != 1:
+			obj[key] = value
+		case jsonParams[key] &&
+			json.Unmarshal([]byte(value[0]), &raw) == nil:
+			obj[key] = raw
+		default:
+			obj[key] = value[0]
+		}
+	}
+	obj["method"] = m.Name()
+	return json.Marshal(obj)
+}
+
+func (b *Bot) handleWebhookUpdate(
+	ctx context.Context,
+	cfg WebhookHandlerCfg,
+	wu webhookUpdate) {
+
+	ctx = context.WithValue(ctx, webhookKey{}, struct{}{})
+	if wu.reply != nil {
+		ctx = context.WithValue(ctx, webhookReplyKey{}, wu.reply)
+	}
+	b.handleUpdate(ctx, &wu.update)
+	if wu.reply != nil {
+		wu.reply.finish()
+	}
+	if cfg.Journal != nil {
+		err := cfg.Journal.Ack(ctx, wu.update.UpdateID)
+		if err != nil && b.errFunc != nil {
+			b.errFunc(WithUpdate(WithAPI(ctx, b.api), &wu.update), err)
+		}
+	}
+}
+
+func (b *Bot) getWebhookHandler(
+	ctx context.Context,
+	cfg WebhookHandlerCfg,
+	dedupe *dedupeWindow,
+	out chan<- webhookUpdate) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			httpError(w, http.StatusMethodNotAllowed)
+			return
+		}
+		if len(cfg.AllowedNetworks) > 0 &&
+			!isAllowedIP(requestIP(r, cfg.RealIPHeader), cfg.AllowedNetworks) {
+
+			httpError(w, http.StatusForbidden)
+			return
+		}
+		if cfg.SecretToken != "" && subtle.ConstantTimeCompare(
+			[]byte(r.Header.Get(SecretTokenHeader)),
+			[]byte(cfg.SecretToken)) != 1 {
+
+			httpError(w, http.StatusUnauthorized)
+			return
+		}
+		if r.ContentLength > cfg.MaxBodySize {
+			httpError(w, http.StatusRequestEntityTooLarge)
+			return
+		}
+		// read one byte more to know that body is too large
+		bytes, err := ioutil.ReadAll(
+			io.LimitReader(r.Body, cfg.MaxBodySize+1))
+		if err != nil {
+			httpError(w, http.StatusBadRequest)
+			return
+		}
+		if int64(len(bytes)) > cfg.MaxBodySize {
+			httpError(w, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var update telegram.Update
+		err = json.Unmarshal(bytes, &update)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if ctx.Err() != nil {
+			httpError(w, http.StatusServiceUnavailable)
+			return
+		}
+		if dedupe.seen(update.UpdateID) {
+			return
+		}
+		if cfg.Journal != nil {
+			if err = cfg.Journal.Append(ctx, update); err != nil {
+				dedupe.forget(update.UpdateID)
+				log.Printf("webhook journal error: %s", err.Error())
+				httpError(w, http.StatusInternalServerError)
+				return
+			}
+		}
+		wu := webhookUpdate{update: update}
+		if cfg.ReplyTimeout > 0 {
+			wu.reply = newWebhookReply()
+		}
+		select {
+		case out <- wu:
+		case <-ctx.Done():
+			// update is replayed from journal if it's set
+			dedupe.forget(update.UpdateID)
+			httpError(w, http.StatusServiceUnavailable)
+			return
+		}
+		if wu.reply == nil {
+			return
+		}
+		timer := time.NewTimer(cfg.ReplyTimeout)
+		defer timer.Stop()
+		select {
+		case <-wu.reply.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		if data := wu.reply.close(); data != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if _, err := w.Write(data); err != nil {
+				log.Printf("webhook reply error: %s", err.Error())
+			}
+		}
+	}
+}
+
+// dedupeWindow remembers the latest update ids.
+// Nil dedupeWindow doesn't remember anything.
+type dedupeWindow struct {
+	mu   sync.Mutex
+	ids  []int64
+	pos  map[int64]int
+	next int
+	full bool
+}
+
+func newDedupeWindow(size int) *dedupeWindow {
+	if size <= 0 {
+		return nil
+	}
+	return &dedupeWindow{
+		ids: make([]int64, size),
+		pos: make(map[int64]int, size),
+	}
+}
+
+// seen returns true if id is in the window, otherwise it adds id
+// and evicts the oldest one if the window is full.
+func (d *dedupeWindow) seen(id int64) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.pos[id]; ok {
+		return true
+	}
+	if d.full {
+		old := d.ids[d.next]
+		if pos, ok := d.pos[old]; ok && pos == d.next {
+			delete(d.pos, old)
+		}
+	}
+	d.ids[d.next] = id
+	d.pos[id] = d.next
+	d.next++
+	if d.next == len(d.ids) {
+		d.next = 0
+		d.full = true
+	}
+	return false
+}
+
+// forget removes id from the window, e.x. if update isn't accepted.
+func (d *dedupeWindow) forget(id int64) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.pos, id)
+}
+
+func httpError(w http.ResponseWriter, code int) {
+	http.Error(w, http.StatusText(code), code)
+}
+
+// requestIP returns request address from header or RemoteAddr.
+// The last address of the header is taken, it's added by
+// a trusted proxy. It returns nil if address can't be parsed.
+func requestIP(r *http.Request, header string) net.IP {
+	if header != "" {
+		values := r.Header[http.CanonicalHeaderKey(header)]
+		if len(values) == 0 {
+			return nil
+		}
+		addrs := strings.Split(values[len(values)-1], ",")
+		return net.ParseIP(strings.TrimSpace(addrs[len(addrs)-1]))
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+func isAllowedIP(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
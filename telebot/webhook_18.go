@@ -0,0 +1,36 @@
+// +build go1.8
+
+package telebot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+type webhookServer struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+func newWebhookServer(ln net.Listener, handler http.Handler) *webhookServer {
+	return &webhookServer{
+		srv: &http.Server{Handler: handler},
+		ln:  ln,
+	}
+}
+
+func (s *webhookServer) serve() error {
+	return s.srv.Serve(s.ln)
+}
+
+// shutdown stops accepting requests and waits for active ones
+// for timeout, then closes all connections.
+func (s *webhookServer) shutdown(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.srv.Close()
+	}
+}
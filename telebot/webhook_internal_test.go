@@ -0,0 +1,837 @@
+package telebot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/m0sth8/httpmock"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func newWebhookRequest(t *testing.T, update interface{}) *http.Request {
+	buf := &bytes.Buffer{}
+	err := json.NewEncoder(buf).Encode(update)
+	require.NoError(t, err)
+	req, err := http.NewRequest("POST", "", buf)
+	require.NoError(t, err)
+	req.RemoteAddr = "149.154.167.197:41234"
+	return req
+}
+
+func TestBot_getWebhookHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+
+	api := telegram.New("token")
+	b := NewWithAPI(api)
+	expUpd := telegram.Update{
+		UpdateID: 10,
+		Message: &telegram.Message{
+			Text: "message",
+		},
+	}
+	ch := make(chan webhookUpdate, 1)
+	cfg := DefaultWebhookHandlerConfig
+	cfg.ReplyTimeout = 0
+	whHandler := b.getWebhookHandler(ctx, cfg, nil, ch)
+
+	{
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, expUpd))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		select {
+		case <-ctx.Done():
+			require.NoError(t, ctx.Err())
+		case upd := <-ch:
+			assert.EqualValues(t, expUpd, upd.update)
+			assert.Nil(t, upd.reply)
+		}
+	}
+	{
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("POST", "",
+			bytes.NewBufferString("bad json"))
+		require.NoError(t, err)
+
+		whHandler(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, 0, len(ch))
+	}
+	{
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "", nil)
+		require.NoError(t, err)
+
+		whHandler(w, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "POST", w.Header().Get("Allow"))
+	}
+}
+
+func TestBot_getWebhookHandler_security(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+
+	b := NewWithAPI(telegram.New("token"))
+	upd := telegram.Update{UpdateID: 10}
+	ch := make(chan webhookUpdate, 1)
+	whHandler := b.getWebhookHandler(ctx, WebhookHandlerCfg{
+		SecretToken:     "secret",
+		AllowedNetworks: TelegramNetworks,
+		MaxBodySize:     100,
+	}, nil, ch)
+
+	testTable := []struct {
+		prepare func(req *http.Request)
+		code    int
+	}{
+		{
+			prepare: func(req *http.Request) {
+				req.Header.Set(SecretTokenHeader, "secret")
+			},
+			code: http.StatusOK,
+		},
+		{
+			prepare: func(req *http.Request) {},
+			code:    http.StatusUnauthorized,
+		},
+		{
+			prepare: func(req *http.Request) {
+				req.Header.Set(SecretTokenHeader, "wrong")
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			prepare: func(req *http.Request) {
+				req.Header.Set(SecretTokenHeader, "secret")
+				req.RemoteAddr = "10.0.0.1:41234"
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			prepare: func(req *http.Request) {
+				req.Header.Set(SecretTokenHeader, "secret")
+				req.RemoteAddr = "bad address"
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			prepare: func(req *http.Request) {
+				req.Header.Set(SecretTokenHeader, "secret")
+				req.Body = ioutil.NopCloser(
+					strings.NewReader(strings.Repeat(" ", 100) + "{}"))
+				req.ContentLength = -1
+			},
+			code: http.StatusRequestEntityTooLarge,
+		},
+		{
+			prepare: func(req *http.Request) {
+				req.Header.Set(SecretTokenHeader, "secret")
+				req.ContentLength = 1000
+			},
+			code: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for i, tt := range testTable {
+		t.Logf("test #%d", i)
+		req := newWebhookRequest(t, upd)
+		tt.prepare(req)
+		w := httptest.NewRecorder()
+		whHandler(w, req)
+		assert.Equal(t, tt.code, w.Code)
+		if w.Code == http.StatusOK {
+			assert.Equal(t, upd, (<-ch).update)
+		}
+		assert.Equal(t, 0, len(ch))
+	}
+
+	// bot is stopped
+	cancel()
+	req := newWebhookRequest(t, upd)
+	req.Header.Set(SecretTokenHeader, "secret")
+	w := httptest.NewRecorder()
+	whHandler(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestRequestIP(t *testing.T) {
+	req, err := http.NewRequest("POST", "", nil)
+	require.NoError(t, err)
+	req.RemoteAddr = "149.154.167.197:41234"
+	// client spoofs the first address, proxy appends the real one
+	req.Header.Set("X-Forwarded-For", "149.154.167.1, 10.0.0.1")
+
+	assert.Equal(t, net.ParseIP("149.154.167.197"), requestIP(req, ""))
+	assert.Equal(t, net.ParseIP("10.0.0.1"),
+		requestIP(req, "X-Forwarded-For"))
+	assert.False(t, isAllowedIP(requestIP(req, "X-Forwarded-For"),
+		TelegramNetworks))
+	assert.Nil(t, requestIP(req, "X-Real-IP"))
+
+	// proxy adds a separate header line
+	req.Header.Add("X-Forwarded-For", "91.108.4.1")
+	assert.Equal(t, net.ParseIP("91.108.4.1"),
+		requestIP(req, "x-forwarded-for"))
+	req.Header.Set("X-Real-IP", "91.108.4.2")
+	assert.Equal(t, net.ParseIP("91.108.4.2"), requestIP(req, "X-Real-IP"))
+
+	assert.True(t, isAllowedIP(net.ParseIP("91.108.4.1"), TelegramNetworks))
+	assert.False(t, isAllowedIP(net.ParseIP("91.108.8.1"), TelegramNetworks))
+	assert.False(t, isAllowedIP(nil, TelegramNetworks))
+}
+
+func TestBot_ServeByWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	api := telegram.New("_token")
+
+	expUpd := []telegram.Update{
+		{
+			UpdateID: 10,
+			Message: &telegram.Message{
+				Text: "message",
+			},
+		},
+		{
+			UpdateID: 11,
+			Message: &telegram.Message{
+				Text: "message",
+			},
+		},
+	}
+	expMe := telegram.User{
+		ID:       10,
+		Username: "test_bot",
+	}
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, expMe),
+	)
+
+	b := NewWithAPI(api)
+
+	handleCh := make(chan context.Context, 1)
+	b.HandleFunc(func(ctx context.Context) error {
+		select {
+		case handleCh <- ctx:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+
+	whHandler, err := b.ServeByWebhook(ctx)
+	require.NoError(t, err)
+
+	{
+		w := httptest.NewRecorder()
+		// prepare request
+		buf := &bytes.Buffer{}
+		err := json.NewEncoder(buf).Encode(expUpd[0])
+		require.NoError(t, err)
+		req, err := http.NewRequest("POST", "", buf)
+		require.NoError(t, err)
+
+		go whHandler(w, req)
+
+	}
+
+	// got first update
+	var update1 *telegram.Update
+	select {
+	case handleCtx1 := <-handleCh:
+		update1 = GetUpdate(handleCtx1)
+	case <-ctx.Done():
+		require.NoError(t, ctx.Err())
+	}
+
+	assert.Equal(t, expMe, *b.me)
+	assert.Equal(t, expUpd[0], *update1)
+
+	{
+		w := httptest.NewRecorder()
+		// prepare request
+		buf := &bytes.Buffer{}
+		err := json.NewEncoder(buf).Encode(expUpd[1])
+		require.NoError(t, err)
+		req, err := http.NewRequest("POST", "", buf)
+		require.NoError(t, err)
+
+		go whHandler(w, req)
+
+	}
+
+	// got second update
+	var update2 *telegram.Update
+	select {
+	case handleCtx1 := <-handleCh:
+		update2 = GetUpdate(handleCtx1)
+	case <-ctx.Done():
+		require.NoError(t, ctx.Err())
+	}
+
+	assert.Equal(t, expUpd[1], *update2)
+
+}
+
+func TestBot_ServeByWebhookWithConfig_reply(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	api := telegram.New("_token")
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+	sentCh := make(chan string, 2)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/sendMessage",
+		func(req *http.Request) (*http.Response, error) {
+			sentCh <- req.FormValue("text")
+			return NewAPIResponder(200, telegram.Message{})(req)
+		},
+	)
+
+	b := NewWithAPI(api)
+	release := make(chan struct{})
+	hold := make(chan struct{})
+	b.HandleFunc(func(ctx context.Context) error {
+		update := GetUpdate(ctx)
+		if update.Message.Text == "slow" {
+			<-release
+		}
+		err := WebhookReply(ctx, telegram.NewMessage(
+			update.Chat().ID, "reply"))
+		if err != nil {
+			return err
+		}
+		if update.Message.Text == "hold" {
+			<-hold
+		}
+		// only the first method is sent in response
+		return WebhookReply(ctx, telegram.NewMessage(
+			update.Chat().ID, "second"))
+	})
+
+	whHandler, err := b.ServeByWebhookWithConfig(ctx, WebhookHandlerCfg{
+		ReplyTimeout: time.Millisecond * 100,
+	})
+	require.NoError(t, err)
+
+	newUpdate := func(text string) telegram.Update {
+		return telegram.Update{
+			UpdateID: 10,
+			Message: &telegram.Message{
+				Chat: telegram.Chat{ID: 1},
+				Text: text,
+			},
+		}
+	}
+	{
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, newUpdate("fast")))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.JSONEq(t,
+			`{"method":"sendMessage","chat_id":"1","text":"reply"}`,
+			w.Body.String())
+		assert.Equal(t, "second", <-sentCh)
+	}
+	{
+		// response is sent as soon as reply is set
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, newUpdate("hold")))
+		assert.JSONEq(t,
+			`{"method":"sendMessage","chat_id":"1","text":"reply"}`,
+			w.Body.String())
+		close(hold)
+		assert.Equal(t, "second", <-sentCh)
+	}
+	{
+		// reply timeout has passed, methods are invoked by api
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, newUpdate("slow")))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "", w.Body.String())
+		close(release)
+		assert.Equal(t, "reply", <-sentCh)
+		assert.Equal(t, "second", <-sentCh)
+	}
+}
+
+func TestEncodeWebhookReply(t *testing.T) {
+	cfg := telegram.NewMessage(1, "[1]")
+	cfg.ReplyMarkup = telegram.InlineKeyboardMarkup{
+		InlineKeyboard: [][]telegram.InlineKeyboardButton{{
+			{Text: "ok", CallbackData: "ok"},
+		}},
+	}
+	data, err := encodeWebhookReply(cfg)
+	require.NoError(t, err)
+	var msg struct {
+		Method      string
+		Text        string
+		ReplyMarkup telegram.InlineKeyboardMarkup `json:"reply_markup"`
+	}
+	require.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "sendMessage", msg.Method)
+	// text isn't decoded even if it's valid json
+	assert.Equal(t, "[1]", msg.Text)
+	assert.Equal(t, cfg.ReplyMarkup, msg.ReplyMarkup)
+
+	data, err = encodeWebhookReply(telegram.AnswerInlineQueryCfg{
+		InlineQueryID: "1",
+		Results: []telegram.InlineQueryResult{
+			telegram.NewInlineQueryResultArticle("a", "title", "text"),
+		},
+	})
+	require.NoError(t, err)
+	var answer struct {
+		Results []struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(data, &answer))
+	require.Len(t, answer.Results, 1)
+	assert.Equal(t, "a", answer.Results[0].ID)
+	assert.Equal(t, "article", answer.Results[0].Type)
+}
+
+func TestWebhookReply(t *testing.T) {
+	reply := newWebhookReply()
+	set, err := reply.set(telegram.NewMessage(1, "text"))
+	assert.True(t, set)
+	assert.NoError(t, err)
+
+	set, err = reply.set(telegram.NewMessage(1, "second"))
+	assert.False(t, set)
+	assert.NoError(t, err)
+
+	// files can't be uploaded in response
+	set, err = newWebhookReply().set(telegram.NewPhotoUpload(
+		1, telegram.NewBytesFile("photo.jpg", nil)))
+	assert.False(t, set)
+	assert.NoError(t, err)
+
+	// method values error
+	set, err = newWebhookReply().set(telegram.NewMessage(1, ""))
+	assert.False(t, set)
+	assert.Equal(t, telegram.NewRequiredError("Text"), err)
+
+	data := reply.close()
+	assert.JSONEq(t,
+		`{"method":"sendMessage","chat_id":"1","text":"text"}`,
+		string(data))
+	// closed reply doesn't take new methods
+	set, err = reply.set(telegram.NewMessage(1, "text"))
+	assert.False(t, set)
+	assert.NoError(t, err)
+}
+
+func TestBot_ServeWebhook(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	client := &http.Client{}
+	api := telegram.NewWithClient("_token", client)
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+	setCh := make(chan url.Values, 1)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/setWebhook",
+		func(req *http.Request) (*http.Response, error) {
+			require.NoError(t, req.ParseForm())
+			setCh <- req.Form
+			return NewAPIResponder(200, true)(req)
+		},
+	)
+	delCh := make(chan url.Values, 1)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/deleteWebhook",
+		func(req *http.Request) (*http.Response, error) {
+			require.NoError(t, req.ParseForm())
+			delCh <- req.Form
+			return NewAPIResponder(200, true)(req)
+		},
+	)
+
+	b := NewWithAPI(api)
+	handleCh := make(chan *telegram.Update, 1)
+	b.HandleFunc(func(ctx context.Context) error {
+		handleCh <- GetUpdate(ctx)
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	serveCtx, serveCancel := context.WithCancel(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- b.ServeWebhook(serveCtx, WebhookServerCfg{
+			Listener: ln,
+			Webhook: telegram.WebhookCfg{
+				URL:         "https://example.com/hook",
+				SecretToken: "secret",
+			},
+			Handler:            DefaultWebhookHandlerConfig,
+			DropPendingUpdates: true,
+		})
+	}()
+
+	select {
+	case form := <-setCh:
+		assert.Equal(t, "https://example.com/hook", form.Get("url"))
+		assert.Equal(t, "secret", form.Get("secret_token"))
+	case err := <-errCh:
+		t.Fatal(err)
+	}
+
+	// a real client, that isn't mocked
+	whClient := &http.Client{Transport: &http.Transport{}}
+	{
+		req := newWebhookRequest(t, telegram.Update{UpdateID: 10})
+		req.URL, err = url.Parse("http://" + ln.Addr().String() + "/hook")
+		require.NoError(t, err)
+		resp, err := whClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		// secret token is taken from webhook config
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+	{
+		req := newWebhookRequest(t, telegram.Update{UpdateID: 11})
+		req.URL, err = url.Parse("http://" + ln.Addr().String() + "/hook")
+		require.NoError(t, err)
+		req.Header.Set(SecretTokenHeader, "secret")
+		resp, err := whClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int64(11), (<-handleCh).UpdateID)
+	}
+	// shutdown waits for fresh connections, that client may keep unused
+	whClient.Transport.(*http.Transport).CloseIdleConnections()
+
+	serveCancel()
+	select {
+	case form := <-delCh:
+		assert.Equal(t, "true", form.Get("drop_pending_updates"))
+	case <-ctx.Done():
+		t.Fatal("webhook should be deleted")
+	}
+	assert.Equal(t, context.Canceled, <-errCh)
+}
+
+func TestBot_ServeWebhook_errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	client := &http.Client{}
+	api := telegram.NewWithClient("_token", client)
+	httpmock.ActivateNonDefault(client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+	setCh := make(chan struct{}, 1)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/setWebhook",
+		func(req *http.Request) (*http.Response, error) {
+			setCh <- struct{}{}
+			return NewAPIResponder(200, true)(req)
+		},
+	)
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/deleteWebhook",
+		httpmock.NewStringResponder(http.StatusInternalServerError,
+			`{"ok":false,"error_code":500,"description":"Internal Server Error"}`),
+	)
+	b := NewWithAPI(api)
+
+	serve := func(ctx context.Context, ln net.Listener) chan error {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- b.ServeWebhook(ctx, WebhookServerCfg{
+				Listener: ln,
+				Webhook:  telegram.WebhookCfg{URL: "https://example.com/hook"},
+			})
+		}()
+		select {
+		case <-setCh:
+		case err := <-errCh:
+			t.Fatal(err)
+		}
+		return errCh
+	}
+
+	// server error is returned before DeleteWebhook error
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	errCh := serve(ctx, ln)
+	require.NoError(t, ln.Close())
+	err = <-errCh
+	assert.Error(t, err)
+	assert.False(t, telegram.IsAPIError(err))
+
+	// DeleteWebhook error is returned on shutdown
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveCtx, serveCancel := context.WithCancel(ctx)
+	errCh = serve(serveCtx, ln)
+	serveCancel()
+	err = <-errCh
+	assert.True(t, telegram.IsAPIError(err))
+}
+
+func TestDedupeWindow(t *testing.T) {
+	var nilWindow *dedupeWindow
+	assert.False(t, nilWindow.seen(1))
+	assert.False(t, nilWindow.seen(1))
+	nilWindow.forget(1)
+	assert.Nil(t, newDedupeWindow(0))
+
+	d := newDedupeWindow(3)
+	for _, id := range []int64{1, 2, 3} {
+		assert.False(t, d.seen(id))
+	}
+	for _, id := range []int64{1, 2, 3} {
+		assert.True(t, d.seen(id))
+	}
+	// 1 is evicted
+	assert.False(t, d.seen(4))
+	assert.False(t, d.seen(1))
+	assert.True(t, d.seen(3))
+
+	// forgotten id can be added again
+	d.forget(3)
+	assert.False(t, d.seen(3))
+	// eviction of the old slot doesn't remove a new one
+	assert.False(t, d.seen(5))
+	assert.True(t, d.seen(3))
+}
+
+// memJournal is an in-memory UpdateJournal
+type memJournal struct {
+	mu        sync.Mutex
+	pending   []telegram.Update
+	acked     []int64
+	appendErr error
+}
+
+func (j *memJournal) Append(_ context.Context, u telegram.Update) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.appendErr != nil {
+		return j.appendErr
+	}
+	j.pending = append(j.pending, u)
+	return nil
+}
+
+func (j *memJournal) Ack(_ context.Context, id int64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.acked = append(j.acked, id)
+	return nil
+}
+
+func (j *memJournal) Pending(context.Context) ([]telegram.Update, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]telegram.Update(nil), j.pending...), nil
+}
+
+func (j *memJournal) getAcked() []int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]int64(nil), j.acked...)
+}
+
+func TestBot_ServeByWebhookWithConfig_dedupe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+
+	b := NewWithAPI(telegram.New("_token"))
+	handleCh := make(chan int64, 10)
+	b.HandleFunc(func(ctx context.Context) error {
+		handleCh <- GetUpdate(ctx).UpdateID
+		return nil
+	})
+	whHandler, err := b.ServeByWebhookWithConfig(ctx, WebhookHandlerCfg{
+		DedupeWindow: 10,
+	})
+	require.NoError(t, err)
+
+	for _, id := range []int64{10, 11, 10, 11, 12} {
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, telegram.Update{UpdateID: id}))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	var handled []int64
+	for len(handled) < 3 {
+		select {
+		case id := <-handleCh:
+			handled = append(handled, id)
+		case <-ctx.Done():
+			t.Fatal("updates weren't handled")
+		}
+	}
+	assert.Equal(t, []int64{10, 11, 12}, handled)
+	assert.Len(t, handleCh, 0)
+}
+
+func TestBot_ServeByWebhook_skipProcessed(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+
+	b := NewWithAPI(telegram.New("_token"))
+	b.Use(SkipProcessed(NewMemoryOffsetStore()))
+	handleCh := make(chan int64, 10)
+	b.HandleFunc(func(ctx context.Context) error {
+		handleCh <- GetUpdate(ctx).UpdateID
+		return nil
+	})
+	whHandler, err := b.ServeByWebhook(ctx)
+	require.NoError(t, err)
+
+	// the same update is delivered twice
+	for _, id := range []int64{10, 10, 11} {
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, telegram.Update{UpdateID: id}))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	var handled []int64
+	for len(handled) < 2 {
+		select {
+		case id := <-handleCh:
+			handled = append(handled, id)
+		case <-ctx.Done():
+			t.Fatal("updates weren't handled")
+		}
+	}
+	assert.Equal(t, []int64{10, 11}, handled)
+	assert.Len(t, handleCh, 0)
+}
+
+func TestBot_ServeByWebhookWithConfig_journal(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
+	defer cancel()
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+	httpmock.RegisterResponder(
+		"POST",
+		"https://api.telegram.org/bot_token/getMe",
+		NewAPIResponder(200, telegram.User{ID: 10}),
+	)
+
+	b := NewWithAPI(telegram.New("_token"))
+	handleCh := make(chan int64, 10)
+	b.HandleFunc(func(ctx context.Context) error {
+		assert.True(t, IsWebhook(ctx))
+		handleCh <- GetUpdate(ctx).UpdateID
+		return nil
+	})
+	journal := &memJournal{
+		pending: []telegram.Update{{UpdateID: 8}, {UpdateID: 9}},
+	}
+	whHandler, err := b.ServeByWebhookWithConfig(ctx, WebhookHandlerCfg{
+		DedupeWindow: 10,
+		Journal:      journal,
+	})
+	require.NoError(t, err)
+
+	// pending updates are known, so redelivery is skipped
+	for _, id := range []int64{9, 10} {
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, telegram.Update{UpdateID: id}))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	var handled []int64
+	for len(handled) < 3 {
+		select {
+		case id := <-handleCh:
+			handled = append(handled, id)
+		case <-ctx.Done():
+			t.Fatal("updates weren't handled")
+		}
+	}
+	// pending updates are replayed first
+	assert.Equal(t, []int64{8, 9, 10}, handled)
+	require.Len(t, journal.pending, 3)
+	assert.Equal(t, int64(10), journal.pending[2].UpdateID)
+	for len(journal.getAcked()) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []int64{8, 9, 10}, journal.getAcked())
+
+	// update isn't accepted if it can't be stored
+	journal.mu.Lock()
+	journal.appendErr = fmt.Errorf("disk is full")
+	journal.mu.Unlock()
+	{
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, telegram.Update{UpdateID: 11}))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	}
+	journal.mu.Lock()
+	journal.appendErr = nil
+	journal.mu.Unlock()
+	{
+		// retry isn't treated as a duplicate
+		w := httptest.NewRecorder()
+		whHandler(w, newWebhookRequest(t, telegram.Update{UpdateID: 11}))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int64(11), <-handleCh)
+	}
+}
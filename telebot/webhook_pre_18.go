@@ -0,0 +1,31 @@
+// +build !go1.8
+
+package telebot
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+type webhookServer struct {
+	handler http.Handler
+	ln      net.Listener
+}
+
+func newWebhookServer(ln net.Listener, handler http.Handler) *webhookServer {
+	return &webhookServer{
+		handler: handler,
+		ln:      ln,
+	}
+}
+
+func (s *webhookServer) serve() error {
+	return http.Serve(s.ln, s.handler)
+}
+
+// shutdown stops accepting requests, http.Server can't wait
+// for active ones before go 1.8.
+func (s *webhookServer) shutdown(time.Duration) {
+	s.ln.Close()
+}
@@ -0,0 +1,663 @@
+package testutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"golang.org/x/net/context"
+)
+
+// Call is an API call received by Server.
+type Call struct {
+	Method string
+	Params url.Values
+}
+
+// CallbackAnswer is an answer to a callback query sent by bot.
+type CallbackAnswer struct {
+	Text      string
+	ShowAlert bool
+}
+
+// Server is a stateful fake Telegram Bot API server for tests.
+// It keeps chats, message history and files,
+// lets tests inject user messages and callback presses
+// and records all API calls made by bot.
+//
+// Server supports getMe, getUpdates, setWebhook, deleteWebhook,
+// getChat, sendMessage, sendPhoto, sendDocument, sendChatAction,
+// forwardMessage, editMessageText, editMessageCaption,
+// editMessageReplyMarkup, answerCallbackQuery, getFile
+// and file downloads. Other methods are answered with 404.
+type Server struct {
+	// Me is a bot user returned by getMe.
+	Me telegram.User
+	// Now returns time for message dates. Optional, time.Now by default.
+	Now func() time.Time
+
+	token     string
+	srv       *httptest.Server
+	transport *http.Transport
+	client    *http.Client
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu sync.Mutex
+	// changed is closed and replaced on every state change.
+	changed    chan struct{}
+	lastID     int64
+	updates    []telegram.Update
+	chats      map[int64]telegram.Chat
+	messages   map[int64][]telegram.Message
+	markups    map[int64]string
+	files      map[string]fakeFile
+	callbacks  map[string]*CallbackAnswer
+	calls      []Call
+	webhookURL string
+}
+
+type fakeFile struct {
+	file telegram.File
+	data []byte
+}
+
+// NewServer starts a fake server for bot with token.
+// Use Server.API to make api client for it. Server should be closed.
+func NewServer(token string) *Server {
+	s := &Server{
+		Me: telegram.User{
+			ID:        1,
+			FirstName: "Test Bot",
+			Username:  "test_bot",
+		},
+		token:     token,
+		closed:    make(chan struct{}),
+		changed:   make(chan struct{}),
+		chats:     make(map[int64]telegram.Chat),
+		messages:  make(map[int64][]telegram.Message),
+		markups:   make(map[int64]string),
+		files:     make(map[string]fakeFile),
+		callbacks: make(map[string]*CallbackAnswer),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	u, _ := url.Parse(s.srv.URL)
+	s.transport = &http.Transport{}
+	s.client = &http.Client{
+		Transport: rewriteTransport{
+			target: u,
+			base:   s.transport,
+		},
+	}
+	return s
+}
+
+// URL returns base url of the server.
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Client returns http client that sends requests
+// to api.telegram.org to the server. The same client is returned
+// every time, its idle connections are closed by Close.
+func (s *Server) Client() *http.Client {
+	return s.client
+}
+
+// API returns api client for the server.
+func (s *Server) API() *telegram.API {
+	return telegram.NewWithClient(s.token, s.Client())
+}
+
+// Close stops the server and interrupts pending getUpdates requests.
+// It's safe to call it several times.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		s.srv.Close()
+		s.transport.CloseIdleConnections()
+	})
+}
+
+// AddChat adds or replaces a chat.
+func (s *Server) AddChat(chat telegram.Chat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chats[chat.ID] = chat
+	s.notify()
+}
+
+// AddUpdate adds a raw update to update queue.
+// UpdateID is set by server.
+func (s *Server) AddUpdate(update telegram.Update) telegram.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addUpdate(update)
+}
+
+// UserMessage adds text message from user to chat history
+// and update queue. Private chat with user is created
+// if chat doesn't exist.
+func (s *Server) UserMessage(chatID int64, from telegram.User, text string) telegram.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[chatID]
+	if !ok {
+		chat = telegram.Chat{
+			ID:        chatID,
+			Type:      telegram.PrivateChatType,
+			FirstName: from.FirstName,
+			LastName:  from.LastName,
+			Username:  from.Username,
+		}
+		s.chats[chatID] = chat
+	}
+	msg := s.addMessage(telegram.Message{
+		From: &from,
+		Chat: chat,
+		Text: text,
+	})
+	s.addUpdate(telegram.Update{Message: &msg})
+	return msg
+}
+
+// PressButton adds callback query from user,
+// that pressed inline button with data under msg.
+// Use CallbackAnswer to check an answer.
+func (s *Server) PressButton(msg telegram.Message, from telegram.User, data string) telegram.CallbackQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	query := telegram.CallbackQuery{
+		ID:      strconv.FormatInt(s.lastID, 10),
+		From:    &from,
+		Message: &msg,
+		Data:    data,
+	}
+	s.callbacks[query.ID] = nil
+	s.addUpdate(telegram.Update{CallbackQuery: &query})
+	return query
+}
+
+// AddFile adds a file that can be received by getFile and downloaded.
+// File id is used as path if path is empty.
+func (s *Server) AddFile(path string, data []byte) telegram.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addFile(path, data)
+}
+
+// Messages returns history of chat.
+func (s *Server) Messages(chatID int64) []telegram.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]telegram.Message(nil), s.messages[chatID]...)
+}
+
+// BotMessages returns messages sent by bot to chat.
+func (s *Server) BotMessages(chatID int64) []telegram.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.botMessages(chatID)
+}
+
+// WaitBotMessages waits until bot sends at least n messages to chat
+// and returns them. It returns ctx.Err() if ctx is done earlier.
+func (s *Server) WaitBotMessages(ctx context.Context, chatID int64, n int) ([]telegram.Message, error) {
+	for {
+		s.mu.Lock()
+		msgs := s.botMessages(chatID)
+		changed := s.changed
+		s.mu.Unlock()
+		if len(msgs) >= n {
+			return msgs, nil
+		}
+		select {
+		case <-ctx.Done():
+			return msgs, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// InlineKeyboard returns inline keyboard of message sent by bot
+// or nil if message doesn't have it.
+func (s *Server) InlineKeyboard(msg telegram.Message) [][]telegram.InlineKeyboardButton {
+	s.mu.Lock()
+	markup := s.markups[msg.MessageID]
+	s.mu.Unlock()
+	var keyboard telegram.InlineKeyboardMarkup
+	if markup == "" || json.Unmarshal([]byte(markup), &keyboard) != nil {
+		return nil
+	}
+	return keyboard.InlineKeyboard
+}
+
+// CallbackAnswer returns answer to callback query with id.
+// It returns false if bot hasn't answered yet.
+func (s *Server) CallbackAnswer(id string) (CallbackAnswer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if answer := s.callbacks[id]; answer != nil {
+		return *answer, true
+	}
+	return CallbackAnswer{}, false
+}
+
+// Calls returns API calls of method, or all calls if method is empty.
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []Call
+	for _, call := range s.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// ============== Internal ================================================== //
+
+type rewriteTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Scheme = t.target.Scheme
+	u.Host = t.target.Host
+	r.URL = &u
+	r.Host = ""
+	return t.base.RoundTrip(r)
+}
+
+// apiError describes error response.
+type apiError struct {
+	code        int
+	description string
+}
+
+func badRequest(format string, args ...interface{}) *apiError {
+	return &apiError{
+		code:        http.StatusBadRequest,
+		description: "Bad Request: " + fmt.Sprintf(format, args...),
+	}
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Server) setMarkup(messageID int64, markup string) {
+	if markup == "" {
+		delete(s.markups, messageID)
+		return
+	}
+	s.markups[messageID] = markup
+}
+
+func (s *Server) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *Server) addUpdate(update telegram.Update) telegram.Update {
+	s.lastID++
+	update.UpdateID = s.lastID
+	s.updates = append(s.updates, update)
+	s.notify()
+	return update
+}
+
+func (s *Server) addMessage(msg telegram.Message) telegram.Message {
+	s.lastID++
+	msg.MessageID = s.lastID
+	msg.Date = int(s.now().Unix())
+	s.messages[msg.Chat.ID] = append(s.messages[msg.Chat.ID], msg)
+	s.notify()
+	return msg
+}
+
+// addFile adds file with path, file id is used as path if it's empty.
+func (s *Server) addFile(path string, data []byte) telegram.File {
+	s.lastID++
+	file := telegram.File{
+		MetaFile: telegram.MetaFile{
+			FileID:   "file" + strconv.FormatInt(s.lastID, 10),
+			FileSize: len(data),
+		},
+		FilePath: path,
+	}
+	if file.FilePath == "" {
+		file.FilePath = file.FileID
+	}
+	s.files[file.FileID] = fakeFile{file: file, data: data}
+	return file
+}
+
+func (s *Server) botMessages(chatID int64) []telegram.Message {
+	var msgs []telegram.Message
+	for _, msg := range s.messages[chatID] {
+		if msg.From != nil && msg.From.ID == s.Me.ID {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if prefix := "/file/bot" + s.token + "/"; strings.HasPrefix(r.URL.Path, prefix) {
+		s.serveFile(w, r, strings.TrimPrefix(r.URL.Path, prefix))
+		return
+	}
+	prefix := "/bot" + s.token + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		writeResponse(w, nil, &apiError{
+			code:        http.StatusUnauthorized,
+			description: "Unauthorized",
+		})
+		return
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil &&
+		err != http.ErrNotMultipart {
+		writeResponse(w, nil, badRequest("%s", err.Error()))
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, prefix)
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Method: method, Params: r.Form})
+	s.mu.Unlock()
+
+	var result interface{}
+	var err *apiError
+	switch method {
+	case "getUpdates":
+		result, err = s.getUpdates(w, r)
+	case "getMe":
+		result = s.Me
+	default:
+		s.mu.Lock()
+		result, err = s.invoke(method, r)
+		s.mu.Unlock()
+	}
+	writeResponse(w, result, err)
+}
+
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request) (interface{}, *apiError) {
+	offset, _ := strconv.ParseInt(r.FormValue("offset"), 10, 64)
+	limit, _ := strconv.Atoi(r.FormValue("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	timeout, _ := strconv.Atoi(r.FormValue("timeout"))
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+	var closeCh <-chan bool
+	if cn, ok := w.(http.CloseNotifier); ok {
+		closeCh = cn.CloseNotify()
+	}
+
+	for {
+		s.mu.Lock()
+		if s.webhookURL != "" {
+			s.mu.Unlock()
+			return nil, &apiError{
+				code: http.StatusConflict,
+				description: "Conflict: can't use getUpdates method " +
+					"while webhook is active",
+			}
+		}
+		// updates with lower ids are confirmed
+		i := 0
+		for i < len(s.updates) && s.updates[i].UpdateID < offset {
+			i++
+		}
+		s.updates = s.updates[i:]
+		updates := s.updates
+		if len(updates) > limit {
+			updates = updates[:limit]
+		}
+		updates = append([]telegram.Update{}, updates...)
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(updates) > 0 || timeout <= 0 {
+			return updates, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return updates, nil
+		case <-closeCh:
+			return updates, nil
+		case <-s.closed:
+			return updates, nil
+		}
+	}
+}
+
+func (s *Server) invoke(method string, r *http.Request) (interface{}, *apiError) {
+	switch method {
+	case "setWebhook":
+		s.webhookURL = r.FormValue("url")
+		return true, nil
+	case "deleteWebhook":
+		s.webhookURL = ""
+		if r.FormValue("drop_pending_updates") == "true" {
+			s.updates = nil
+		}
+		return true, nil
+	case "getChat":
+		return s.chat(r.FormValue("chat_id"))
+	case "sendChatAction":
+		_, err := s.chat(r.FormValue("chat_id"))
+		return err == nil, err
+	case "sendMessage":
+		if r.FormValue("text") == "" {
+			return nil, badRequest("message text is empty")
+		}
+		return s.send(r, telegram.Message{Text: r.FormValue("text")})
+	case "sendPhoto":
+		fileID, err := s.inputFile(r, "photo")
+		if err != nil {
+			return nil, err
+		}
+		return s.send(r, telegram.Message{
+			Photo: []telegram.PhotoSize{
+				{MetaFile: telegram.MetaFile{FileID: fileID}},
+			},
+			Caption: r.FormValue("caption"),
+		})
+	case "sendDocument":
+		fileID, err := s.inputFile(r, "document")
+		if err != nil {
+			return nil, err
+		}
+		return s.send(r, telegram.Message{
+			Document: &telegram.Document{
+				MetaFile: telegram.MetaFile{FileID: fileID},
+			},
+			Caption: r.FormValue("caption"),
+		})
+	case "forwardMessage":
+		from, err := s.message(r.FormValue("from_chat_id"), r.FormValue("message_id"))
+		if err != nil {
+			return nil, err
+		}
+		msg := *from
+		msg.ForwardFrom = from.From
+		msg.ForwardDate = from.Date
+		return s.send(r, msg)
+	case "editMessageText", "editMessageCaption", "editMessageReplyMarkup":
+		return s.edit(method, r)
+	case "answerCallbackQuery":
+		id := r.FormValue("callback_query_id")
+		answer, ok := s.callbacks[id]
+		if !ok || answer != nil {
+			return nil, badRequest("query is too old and response timeout expired or query ID is invalid")
+		}
+		s.callbacks[id] = &CallbackAnswer{
+			Text:      r.FormValue("text"),
+			ShowAlert: r.FormValue("show_alert") == "true",
+		}
+		s.notify()
+		return true, nil
+	case "getFile":
+		f, ok := s.files[r.FormValue("file_id")]
+		if !ok {
+			return nil, badRequest("invalid file_id")
+		}
+		return f.file, nil
+	}
+	return nil, &apiError{
+		code:        http.StatusNotFound,
+		description: "Not Found",
+	}
+}
+
+func (s *Server) chat(chatID string) (telegram.Chat, *apiError) {
+	id, _ := strconv.ParseInt(chatID, 10, 64)
+	chat, ok := s.chats[id]
+	if !ok {
+		return chat, badRequest("chat not found")
+	}
+	return chat, nil
+}
+
+func (s *Server) message(chatID, messageID string) (*telegram.Message, *apiError) {
+	chat, err := s.chat(chatID)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := strconv.ParseInt(messageID, 10, 64)
+	msgs := s.messages[chat.ID]
+	for i := range msgs {
+		if msgs[i].MessageID == id {
+			return &msgs[i], nil
+		}
+	}
+	return nil, badRequest("message to edit not found")
+}
+
+// inputFile returns file id of an existing or uploaded file.
+func (s *Server) inputFile(r *http.Request, field string) (string, *apiError) {
+	if fileID := r.FormValue(field); fileID != "" {
+		if _, ok := s.files[fileID]; !ok {
+			return "", badRequest("wrong file identifier")
+		}
+		return fileID, nil
+	}
+	if r.MultipartForm == nil || len(r.MultipartForm.File[field]) == 0 {
+		return "", badRequest("there is no %s in the request", field)
+	}
+	header := r.MultipartForm.File[field][0]
+	f, err := header.Open()
+	if err != nil {
+		return "", badRequest("%s", err.Error())
+	}
+	defer f.Close()
+	buf, rErr := ioutil.ReadAll(f)
+	if rErr != nil {
+		return "", badRequest("%s", rErr.Error())
+	}
+	// uploads with the same name are different files
+	return s.addFile("", buf).FileID, nil
+}
+
+func (s *Server) send(r *http.Request, msg telegram.Message) (interface{}, *apiError) {
+	chat, err := s.chat(r.FormValue("chat_id"))
+	if err != nil {
+		return nil, err
+	}
+	me := s.Me
+	msg.From = &me
+	msg.Chat = chat
+	if replyTo := r.FormValue("reply_to_message_id"); replyTo != "" {
+		if msg.ReplyToMessage, err = s.message(r.FormValue("chat_id"), replyTo); err != nil {
+			return nil, err
+		}
+	}
+	msg = s.addMessage(msg)
+	s.setMarkup(msg.MessageID, r.FormValue("reply_markup"))
+	return msg, nil
+}
+
+func (s *Server) edit(method string, r *http.Request) (interface{}, *apiError) {
+	if r.FormValue("inline_message_id") != "" {
+		return true, nil
+	}
+	msg, err := s.message(r.FormValue("chat_id"), r.FormValue("message_id"))
+	if err != nil {
+		return nil, err
+	}
+	if msg.From == nil || msg.From.ID != s.Me.ID {
+		return nil, badRequest("message can't be edited")
+	}
+	switch method {
+	case "editMessageText":
+		msg.Text = r.FormValue("text")
+		s.setMarkup(msg.MessageID, r.FormValue("reply_markup"))
+	case "editMessageCaption":
+		msg.Caption = r.FormValue("caption")
+		s.setMarkup(msg.MessageID, r.FormValue("reply_markup"))
+	}
+	if method == "editMessageReplyMarkup" {
+		s.setMarkup(msg.MessageID, r.FormValue("reply_markup"))
+	}
+	msg.EditDate = int(s.now().Unix())
+	s.notify()
+	return *msg, nil
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range s.files {
+		if f.file.FilePath == path {
+			if _, err := w.Write(f.data); err != nil {
+				log.Printf("fake server file error: %s", err.Error())
+			}
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func writeResponse(w http.ResponseWriter, result interface{}, apiErr *apiError) {
+	resp := telegram.APIResponse{Ok: apiErr == nil}
+	code := http.StatusOK
+	if apiErr != nil {
+		code = apiErr.code
+		resp.ErrorCode = apiErr.code
+		resp.Description = apiErr.description
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		raw := json.RawMessage(data)
+		resp.Result = &raw
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("fake server response error: %s", err.Error())
+	}
+}
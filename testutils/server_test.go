@@ -0,0 +1,170 @@
+package testutils_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bot-api/telegram"
+	"github.com/bot-api/telegram/telebot"
+	"github.com/bot-api/telegram/testutils"
+	"golang.org/x/net/context"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestServer_bot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	srv := testutils.NewServer("token")
+	defer srv.Close()
+
+	b := telebot.NewWithAPI(srv.API())
+	b.Use(telebot.Callbacks(map[string]telebot.InlineCallback{
+		"like": telebot.CallbackFunc(
+			func(ctx context.Context, arg string) error {
+				query := telebot.GetUpdate(ctx).CallbackQuery
+				api := telebot.GetAPI(ctx)
+				_, err := api.EditMessageText(ctx, telegram.NewEditMessageText(
+					query.Message.Chat.ID,
+					query.Message.MessageID,
+					"liked "+arg))
+				if err != nil {
+					return err
+				}
+				_, err = api.AnswerCallbackQuery(ctx,
+					telegram.NewAnswerCallback(query.ID, "thanks"))
+				return err
+			}),
+	}))
+	b.HandleFunc(func(ctx context.Context) error {
+		msg := telebot.GetUpdate(ctx).Message
+		cfg := telegram.NewMessage(msg.Chat.ID, "echo: "+msg.Text)
+		_, err := telebot.GetAPI(ctx).SendMessage(ctx, cfg)
+		return err
+	})
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- b.Serve(ctx)
+	}()
+
+	user := telegram.User{ID: 100, FirstName: "John"}
+	srv.UserMessage(100, user, "hello")
+	msgs, err := srv.WaitBotMessages(ctx, 100, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hello", msgs[0].Text)
+	assert.Equal(t, srv.Me, *msgs[0].From)
+	assert.Equal(t, telegram.PrivateChatType, msgs[0].Chat.Type)
+
+	history := srv.Messages(100)
+	require.Len(t, history, 2)
+	assert.Equal(t, "hello", history[0].Text)
+
+	query := srv.PressButton(msgs[0], user, "like:5")
+	for {
+		if _, ok := srv.CallbackAnswer(query.ID); ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("callback query wasn't answered")
+		case <-time.After(time.Millisecond * 5):
+		}
+	}
+	answer, _ := srv.CallbackAnswer(query.ID)
+	assert.Equal(t, testutils.CallbackAnswer{Text: "thanks"}, answer)
+	assert.Equal(t, "liked 5", srv.BotMessages(100)[0].Text)
+	assert.NotZero(t, srv.BotMessages(100)[0].EditDate)
+
+	calls := srv.Calls("editMessageText")
+	require.Len(t, calls, 1)
+	assert.Equal(t, "liked 5", calls[0].Params.Get("text"))
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-serveErr)
+}
+
+func TestServer_api(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	srv := testutils.NewServer("token")
+	defer srv.Close()
+	api := srv.API()
+
+	// unknown chat
+	_, err := api.SendMessage(ctx, telegram.NewMessage(1, "text"))
+	require.Error(t, err)
+	assert.Equal(t, "apiError: Bad Request: chat not found", err.Error())
+
+	srv.AddChat(telegram.Chat{ID: 1, Type: telegram.GroupChatType})
+	chat, err := api.GetChat(ctx, telegram.GetChatCfg{
+		BaseChat: telegram.BaseChat{ID: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, telegram.GroupChatType, chat.Type)
+
+	// upload a photo and download it
+	msg, err := api.SendPhoto(ctx, telegram.NewPhotoUpload(1,
+		telegram.NewBytesFile("photo.jpg", []byte("photo data"))))
+	require.NoError(t, err)
+	require.Len(t, msg.Photo, 1)
+	buf := &bytes.Buffer{}
+	err = api.DownloadFile(ctx, telegram.FileCfg{
+		FileID: msg.Photo[0].FileID}, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "photo data", buf.String())
+
+	// uploads with the same name don't overwrite each other
+	msg2, err := api.SendPhoto(ctx, telegram.NewPhotoUpload(1,
+		telegram.NewBytesFile("photo.jpg", []byte("another photo"))))
+	require.NoError(t, err)
+	for fileID, data := range map[string]string{
+		msg.Photo[0].FileID:  "photo data",
+		msg2.Photo[0].FileID: "another photo",
+	} {
+		buf.Reset()
+		err = api.DownloadFile(ctx, telegram.FileCfg{FileID: fileID}, buf)
+		require.NoError(t, err)
+		assert.Equal(t, data, buf.String())
+	}
+	assert.True(t, srv.Client() == srv.Client())
+
+	file := srv.AddFile("docs/file.txt", []byte("text"))
+	msg, err = api.SendDocument(ctx, telegram.DocumentCfg{
+		BaseFile: telegram.BaseFile{
+			BaseMessage: telegram.BaseMessage{
+				BaseChat: telegram.BaseChat{ID: 1},
+			},
+			FileID: file.FileID,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, file.FileID, msg.Document.FileID)
+
+	// getUpdates doesn't work with webhook
+	require.NoError(t, api.SetWebhook(ctx, telegram.NewWebhook("https://example.com")))
+	_, err = api.GetUpdates(ctx, telegram.UpdateCfg{})
+	assert.True(t, telegram.IsFatalError(err), "%v", err)
+	require.NoError(t, api.DeleteWebhook(ctx, telegram.DeleteWebhookCfg{}))
+
+	// long polling returns when update is added
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		srv.AddUpdate(telegram.Update{})
+	}()
+	updates, err := api.GetUpdates(ctx, telegram.UpdateCfg{Timeout: 10})
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	// updates are confirmed by offset
+	updates, err = api.GetUpdates(ctx, telegram.UpdateCfg{
+		Offset: updates[0].UpdateID + 1})
+	require.NoError(t, err)
+	assert.Len(t, updates, 0)
+
+	// unauthorized
+	_, err = telegram.NewWithClient("wrong", srv.Client()).GetMe(ctx)
+	assert.True(t, telegram.IsUnauthorizedError(err))
+
+	// server can be closed twice
+	srv.Close()
+	srv.Close()
+}
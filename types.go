@@ -14,6 +14,33 @@ type APIResponse struct {
 	Result      *json.RawMessage `json:"result"`
 	ErrorCode   int              `json:"error_code,omitempty"`
 	Description string           `json:"description,omitempty"`
+	// Optional. Helps to automatically handle the error.
+	Parameters *ResponseParameters `json:"parameters,omitempty"`
+}
+
+// ResponseParameters contains information about why a request
+// was unsuccessful.
+type ResponseParameters struct {
+	// Optional. The group has been migrated to a supergroup
+	// with the specified identifier.
+	MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"`
+	// Optional. In case of exceeding flood control, the number of
+	// seconds left to wait before the request can be repeated.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// ExtraFields contains json object fields unknown to this library
+// version, keyed by json name. Structs keep a pointer to it,
+// so they stay comparable.
+type ExtraFields map[string]json.RawMessage
+
+// Get returns raw json value of field name
+// or nil if there is no such field. It's safe to call on nil.
+func (e *ExtraFields) Get(name string) json.RawMessage {
+	if e == nil {
+		return nil
+	}
+	return (*e)[name]
 }
 
 // Update object represents an incoming update.
@@ -36,6 +63,25 @@ type Update struct {
 	ChosenInlineResult *ChosenInlineResult `json:"chosen_inline_result,omitempty"`
 	// CallbackQuery is a new incoming callback query. Optional.
 	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+
+	// Extra contains fields that are unknown to this library version.
+	// It lets handlers read new API fields before typed support
+	// is added. Optional.
+	Extra *ExtraFields `json:"-"`
+}
+
+// UnmarshalJSON decodes update and keeps unknown fields in Extra.
+func (u *Update) UnmarshalJSON(data []byte) error {
+	type plain Update
+	extra, err := unmarshalExtra(data, (*plain)(u))
+	u.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes update together with fields from Extra.
+func (u Update) MarshalJSON() ([]byte, error) {
+	type plain Update
+	return marshalExtra(plain(u), u.Extra)
 }
 
 // HasMessage returns true if update object contains Message field
@@ -143,6 +189,10 @@ type Message struct {
 	// Caption for the document, photo or video, 0‐200 characters
 	Caption string `json:"caption,omitempty"`
 
+	// For messages with a caption, special entities like usernames,
+	// URLs, bot commands, etc. that appear in the caption. Optional
+	CaptionEntities []MessageEntity `json:"caption_entities,omitempty"`
+
 	// For a contact, contact information itself.
 	Contact *Contact `json:"contact,omitempty"`
 
@@ -226,6 +276,25 @@ type Message struct {
 	// will not contain further reply_to_message fields
 	// even if it is itself a reply.
 	PinnedMessage *Message `json:"pinned_message,omitempty"`
+
+	// Extra contains fields that are unknown to this library version.
+	// It lets handlers read new API fields before typed support
+	// is added. Optional.
+	Extra *ExtraFields `json:"-"`
+}
+
+// UnmarshalJSON decodes message and keeps unknown fields in Extra.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	extra, err := unmarshalExtra(data, (*plain)(m))
+	m.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes message together with fields from Extra.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	return marshalExtra(plain(m), m.Extra)
 }
 
 // IsCommand returns true if message starts with '/'.
@@ -298,6 +367,9 @@ type MessageEntity struct {
 	URL string `json:"url,omitempty"`
 	// For “text_mention” only, the mentioned user. Optional.
 	User *User `json:"user,omitempty"`
+	// For “pre” only, the programming language of the entity text.
+	// Optional.
+	Language string `json:"language,omitempty"`
 }
 
 // User object represents a Telegram user or bot.
@@ -312,6 +384,25 @@ type User struct {
 	LastName string `json:"last_name,omitempty"`
 	// Username is a user‘s or bot’s username. Optional.
 	Username string `json:"username,omitempty"`
+
+	// Extra contains fields that are unknown to this library version.
+	// It lets handlers read new API fields before typed support
+	// is added. Optional.
+	Extra *ExtraFields `json:"-"`
+}
+
+// UnmarshalJSON decodes user and keeps unknown fields in Extra.
+func (u *User) UnmarshalJSON(data []byte) error {
+	type plain User
+	extra, err := unmarshalExtra(data, (*plain)(u))
+	u.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes user together with fields from Extra.
+func (u User) MarshalJSON() ([]byte, error) {
+	type plain User
+	return marshalExtra(plain(u), u.Extra)
 }
 
 // Chat object represents a Telegram user, bot or group chat.
@@ -326,6 +417,25 @@ type Chat struct {
 	FirstName string `json:"first_name,omitempty"`
 	LastName  string `json:"last_name,omitempty"`
 	Username  string `json:"username,omitempty"`
+
+	// Extra contains fields that are unknown to this library version.
+	// It lets handlers read new API fields before typed support
+	// is added. Optional.
+	Extra *ExtraFields `json:"-"`
+}
+
+// UnmarshalJSON decodes chat and keeps unknown fields in Extra.
+func (c *Chat) UnmarshalJSON(data []byte) error {
+	type plain Chat
+	extra, err := unmarshalExtra(data, (*plain)(c))
+	c.Extra = extra
+	return err
+}
+
+// MarshalJSON encodes chat together with fields from Extra.
+func (c Chat) MarshalJSON() ([]byte, error) {
+	type plain Chat
+	return marshalExtra(plain(c), c.Extra)
 }
 
 // ChatMember object contains information about one member of the chat.
@@ -538,6 +648,35 @@ type CallbackQuery struct {
 	Data string `json:"data"`
 }
 
+// WebhookInfo contains information about the current status of a webhook.
+type WebhookInfo struct {
+	// Webhook URL, may be empty if webhook is not set up
+	URL string `json:"url"`
+	// True, if a custom certificate was provided
+	// for webhook certificate checks
+	HasCustomCertificate bool `json:"has_custom_certificate"`
+	// Number of updates awaiting delivery
+	PendingUpdateCount int `json:"pending_update_count"`
+	// Currently used webhook IP address. Optional.
+	IPAddress string `json:"ip_address,omitempty"`
+	// Unix time for the most recent error that happened
+	// when trying to deliver an update via webhook. Optional.
+	LastErrorDate int `json:"last_error_date,omitempty"`
+	// Error message in human-readable format for the most recent error
+	// that happened when trying to deliver an update via webhook. Optional.
+	LastErrorMessage string `json:"last_error_message,omitempty"`
+	// Unix time of the most recent error that happened
+	// when trying to synchronize available updates
+	// with Telegram datacenters. Optional.
+	LastSynchronizationErrorDate int `json:"last_synchronization_error_date,omitempty"`
+	// Maximum allowed number of simultaneous HTTPS connections
+	// to the webhook for update delivery. Optional.
+	MaxConnections int `json:"max_connections,omitempty"`
+	// A list of update types the bot is subscribed to.
+	// Defaults to all update types except chat_member. Optional.
+	AllowedUpdates []string `json:"allowed_updates,omitempty"`
+}
+
 // ======= Markups
 
 // A MarkReplyMarkup implements ReplyMarkup interface.
@@ -565,6 +704,30 @@ type KeyboardButton struct {
 	// when the button is pressed. Available in private chats only.
 	// Optional.
 	RequestLocation bool `json:"request_location,omitempty"`
+	// If specified, the user will be asked to create a poll
+	// and send it to the bot when the button is pressed.
+	// Available in private chats only. Optional.
+	RequestPoll *KeyboardButtonPollType `json:"request_poll,omitempty"`
+	// If specified, the described Web App will be launched
+	// when the button is pressed. Available in private chats only.
+	// Optional.
+	WebApp *WebAppInfo `json:"web_app,omitempty"`
+}
+
+// KeyboardButtonPollType represents type of a poll, which is allowed
+// to be created and sent when the corresponding button is pressed.
+type KeyboardButtonPollType struct {
+	// If "quiz" is passed, the user will be allowed to create
+	// only polls in the quiz mode. If "regular" is passed,
+	// only regular polls will be allowed. Otherwise,
+	// the user will be allowed to create a poll of any type.
+	Type string `json:"type,omitempty"`
+}
+
+// WebAppInfo describes a Web App.
+type WebAppInfo struct {
+	// An HTTPS URL of a Web App to be opened.
+	URL string `json:"url"`
 }
 
 // ReplyKeyboardMarkup represents a custom keyboard with reply options.
@@ -587,6 +750,14 @@ type ReplyKeyboardMarkup struct {
 	// a special button in the input field to see the custom keyboard again.
 	// Defaults to false.
 	OneTimeKeyboard bool `json:"one_time_keyboard,omitempty"`
+	// Requests clients to always show the keyboard
+	// when the regular keyboard is hidden.
+	// Defaults to false, in which case the custom keyboard
+	// can be hidden and opened with a keyboard icon.
+	IsPersistent bool `json:"is_persistent,omitempty"`
+	// The placeholder to be shown in the input field
+	// when the keyboard is active; 1-64 characters. Optional.
+	InputFieldPlaceholder string `json:"input_field_placeholder,omitempty"`
 	// Use this parameter if you want to show the keyboard
 	// to specific users only.
 	// Targets:
@@ -599,6 +770,9 @@ type ReplyKeyboardMarkup struct {
 // ReplyKeyboardHide tells Telegram clients to hide the current
 // custom keyboard and display the default letter-keyboard.
 // Implements ReplyMarkup interface.
+//
+// Deprecated: hide_keyboard isn't supported by Bot API anymore,
+// use ReplyKeyboardRemove.
 type ReplyKeyboardHide struct {
 	MarkReplyMarkup
 
@@ -606,6 +780,20 @@ type ReplyKeyboardHide struct {
 	Selective    bool `json:"selective"` // optional
 }
 
+// ReplyKeyboardRemove tells Telegram clients to remove the current
+// custom keyboard and display the default letter-keyboard.
+// Implements ReplyMarkup interface.
+type ReplyKeyboardRemove struct {
+	MarkReplyMarkup
+
+	// Requests clients to remove the custom keyboard, must be true.
+	RemoveKeyboard bool `json:"remove_keyboard"`
+	// Use this parameter if you want to remove the keyboard
+	// for specific users only, see ReplyKeyboardMarkup.Selective.
+	// Optional.
+	Selective bool `json:"selective,omitempty"`
+}
+
 // ForceReply allows the Bot to have users directly reply to it without
 // additional interaction.
 // Implements ReplyMarkup interface.
@@ -643,7 +831,84 @@ type InlineKeyboardButton struct {
 	// Especially useful when combined with switch_pm... actions
 	// – in this case the user will be automatically returned to the chat
 	// they switched from, skipping the chat selection screen.
+	//
+	// Empty query is sent only for buttons made by
+	// InlineKeyboardBuilder.SwitchInline or decoded from json.
 	SwitchInlineQuery string `json:"switch_inline_query,omitempty"`
+	// If set, pressing the button will insert the bot‘s username
+	// and the specified inline query in the current chat's input field.
+	// Optional. Empty query is sent only for buttons made by
+	// InlineKeyboardBuilder.SwitchInlineCurrentChat or decoded from json.
+	SwitchInlineQueryCurrentChat string `json:"switch_inline_query_current_chat,omitempty"`
+	// An HTTP URL used to automatically authorize the user. Optional.
+	LoginURL *LoginURL `json:"login_url,omitempty"`
+	// Specify True, to send a Pay button.
+	// It must always be the first button in the first row. Optional.
+	Pay bool `json:"pay,omitempty"`
+
+	// switchEmpty and switchCurrentChatEmpty mark switch buttons
+	// with empty query, so the query isn't omitted.
+	switchEmpty            bool
+	switchCurrentChatEmpty bool
+}
+
+// MarshalJSON encodes button with switch query
+// even if it's empty for switch buttons.
+func (b InlineKeyboardButton) MarshalJSON() ([]byte, error) {
+	type plain InlineKeyboardButton
+	v := struct {
+		plain
+		SwitchInlineQuery            *string `json:"switch_inline_query,omitempty"`
+		SwitchInlineQueryCurrentChat *string `json:"switch_inline_query_current_chat,omitempty"`
+	}{plain: plain(b)}
+	if b.SwitchInlineQuery != "" || b.switchEmpty {
+		v.SwitchInlineQuery = &b.SwitchInlineQuery
+	}
+	if b.SwitchInlineQueryCurrentChat != "" || b.switchCurrentChatEmpty {
+		v.SwitchInlineQueryCurrentChat = &b.SwitchInlineQueryCurrentChat
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes button and remembers switch queries
+// that are empty.
+func (b *InlineKeyboardButton) UnmarshalJSON(data []byte) error {
+	type plain InlineKeyboardButton
+	v := struct {
+		*plain
+		SwitchInlineQuery            *string `json:"switch_inline_query"`
+		SwitchInlineQueryCurrentChat *string `json:"switch_inline_query_current_chat"`
+	}{plain: (*plain)(b)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	b.SwitchInlineQuery, b.switchEmpty = "", false
+	if v.SwitchInlineQuery != nil {
+		b.SwitchInlineQuery = *v.SwitchInlineQuery
+		b.switchEmpty = b.SwitchInlineQuery == ""
+	}
+	b.SwitchInlineQueryCurrentChat, b.switchCurrentChatEmpty = "", false
+	if v.SwitchInlineQueryCurrentChat != nil {
+		b.SwitchInlineQueryCurrentChat = *v.SwitchInlineQueryCurrentChat
+		b.switchCurrentChatEmpty = b.SwitchInlineQueryCurrentChat == ""
+	}
+	return nil
+}
+
+// LoginURL represents a parameter of the inline keyboard button
+// used to automatically authorize a user.
+type LoginURL struct {
+	// An HTTP URL to be opened with user authorization data
+	// added to the query string when the button is pressed.
+	URL string `json:"url"`
+	// New text of the button in forwarded messages. Optional.
+	ForwardText string `json:"forward_text,omitempty"`
+	// Username of a bot, which will be used for user authorization.
+	// Optional.
+	BotUsername string `json:"bot_username,omitempty"`
+	// Pass True to request the permission for your bot
+	// to send messages to the user. Optional.
+	RequestWriteAccess bool `json:"request_write_access,omitempty"`
 }
 
 // InlineKeyboardMarkup object represents an inline keyboard
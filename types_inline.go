@@ -24,6 +24,13 @@ type ChosenInlineResult struct {
 	ResultID string `json:"result_id"`
 	// From is a user that chose the result.
 	From User `json:"from"`
+	// Sender location, only for bots that require user location.
+	// Optional.
+	Location *Location `json:"location,omitempty"`
+	// Identifier of the sent inline message. Available only
+	// if there is an inline keyboard attached to the message.
+	// It can be used to edit the message. Optional.
+	InlineMessageID string `json:"inline_message_id,omitempty"`
 	// Query is used to obtain the result.
 	Query string `json:"query"`
 }
@@ -44,6 +51,12 @@ type BaseInlineQueryResult struct {
 	ReplyMarkup ReplyMarkup `json:"reply_markup,omitempty"`
 }
 
+// ResultID returns ID of the result,
+// it's promoted to all result types.
+func (r BaseInlineQueryResult) ResultID() string {
+	return r.ID
+}
+
 // InlineThumb struct helps to describe thumbnail.
 type InlineThumb struct {
 	ThumbURL    string `json:"thumb_url,omitempty"`
@@ -0,0 +1,90 @@
+package telegram_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bot-api/telegram"
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+func TestUpdate_Extra(t *testing.T) {
+	data := `{
+		"update_id": 10,
+		"new_update_kind": {"id":1},
+		"message": {
+			"message_id": 2,
+			"date": 1478026519,
+			"text": "hi",
+			"reply_markup": {"inline_keyboard":[]},
+			"from": {"id": 3, "first_name": "John", "is_bot": false},
+			"chat": {"id": 3, "type": "private", "all_members_are_administrators": true}
+		}
+	}`
+	update := telegram.Update{}
+	require.NoError(t, json.Unmarshal([]byte(data), &update))
+
+	assert.Equal(t, int64(10), update.UpdateID)
+	assert.Equal(t, &telegram.ExtraFields{
+		"new_update_kind": json.RawMessage(`{"id":1}`),
+	}, update.Extra)
+	assert.Equal(t, json.RawMessage(`{"id":1}`),
+		update.Extra.Get("new_update_kind"))
+	require.NotNil(t, update.Message)
+	assert.Equal(t, "hi", update.Message.Text)
+	assert.Equal(t, &telegram.ExtraFields{
+		"reply_markup": json.RawMessage(`{"inline_keyboard":[]}`),
+	}, update.Message.Extra)
+	assert.Equal(t, &telegram.ExtraFields{
+		"is_bot": json.RawMessage(`false`),
+	}, update.Message.From.Extra)
+	assert.Equal(t, &telegram.ExtraFields{
+		"all_members_are_administrators": json.RawMessage(`true`),
+	}, update.Message.Chat.Extra)
+
+	encoded, err := json.Marshal(update)
+	require.NoError(t, err)
+	decoded := telegram.Update{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	assert.Equal(t, update, decoded)
+}
+
+func TestUser_Extra(t *testing.T) {
+	user := telegram.User{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":1,"first_name":"John"}`), &user))
+	assert.Equal(t, telegram.User{ID: 1, FirstName: "John"}, user)
+
+	assert.Nil(t, user.Extra.Get("first_name"))
+
+	// previous extra fields are reset
+	user.Extra = &telegram.ExtraFields{"a": json.RawMessage(`1`)}
+	require.NoError(t, json.Unmarshal([]byte(`{"id":2,"first_name":"Jane"}`), &user))
+	assert.Nil(t, user.Extra)
+
+	// fields are matched case-insensitively like in json.Unmarshal
+	require.NoError(t, json.Unmarshal([]byte(`{"ID":3,"First_Name":"Bob"}`), &user))
+	assert.Equal(t, telegram.User{ID: 3, FirstName: "Bob"}, user)
+
+	// structs stay comparable
+	users := map[telegram.User]bool{user: true}
+	assert.True(t, users[telegram.User{ID: 3, FirstName: "Bob"}])
+
+	// known fields in Extra are skipped
+	user = telegram.User{ID: 2, FirstName: "Jane"}
+	user.Extra = &telegram.ExtraFields{
+		"First_Name":    json.RawMessage(`"Bob"`),
+		"language_code": json.RawMessage(`"en"`),
+	}
+	encoded, err := json.Marshal(user)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":2,"first_name":"Jane","language_code":"en"}`,
+		string(encoded))
+
+	user.Extra = &telegram.ExtraFields{"bad": json.RawMessage(`{`)}
+	_, err = json.Marshal(user)
+	assert.Error(t, err)
+
+	chat := telegram.Chat{}
+	assert.Error(t, json.Unmarshal([]byte(`{"id":"1"}`), &chat))
+}
@@ -1,12 +1,20 @@
 package telegram
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/url"
+	"reflect"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 )
 
 var tokenRegex = regexp.MustCompile(`^[\d]{3,11}:[\w-]{35}$`)
 
+var secretTokenRegex = regexp.MustCompile(`^[\w-]{1,256}$`)
+
 // IsValidToken returns true if token is a valid telegram bot token
 //
 // Token format is like: 110201543:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsawq
@@ -24,6 +32,20 @@ func updateValues(to, from url.Values) {
 	}
 }
 
+// addEntities adds entities encoded as json array to v,
+// nothing is added if there are no entities.
+func addEntities(v url.Values, field string, entities []MessageEntity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(entities)
+	if err != nil {
+		return err
+	}
+	v.Add(field, string(data))
+	return nil
+}
+
 func updateValuesWithPrefix(to, from url.Values, prefix string) {
 	for key, values := range from {
 		for _, value := range values {
@@ -31,3 +53,94 @@ func updateValuesWithPrefix(to, from url.Values, prefix string) {
 		}
 	}
 }
+
+// unmarshalExtra decodes json object data into v, that is a pointer
+// to plain struct, and returns object fields unknown to v or nil
+// if there are none. Like json.Unmarshal, it keeps decoding
+// after type errors and returns the first one.
+func unmarshalExtra(data []byte, v interface{}) (*ExtraFields, error) {
+	err := json.Unmarshal(data, v)
+	if _, ok := err.(*json.UnmarshalTypeError); err != nil && !ok {
+		return nil, err
+	}
+	var extra ExtraFields
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return nil, err
+	}
+	known := jsonNamesOf(reflect.TypeOf(v))
+	for name := range extra {
+		if known[strings.ToLower(name)] {
+			delete(extra, name)
+		}
+	}
+	if len(extra) == 0 {
+		return nil, err
+	}
+	return &extra, err
+}
+
+// marshalExtra encodes v, that is a plain struct, and appends extra fields
+// to the object. Extra fields that are known to v are skipped.
+func marshalExtra(v interface{}, extra *ExtraFields) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || extra == nil || len(*extra) == 0 {
+		return data, err
+	}
+	known := jsonNamesOf(reflect.TypeOf(v))
+	names := make([]string, 0, len(*extra))
+	for name := range *extra {
+		if !known[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for _, name := range names {
+		value, err := json.Marshal((*extra)[name])
+		if err != nil {
+			return nil, err
+		}
+		key, _ := json.Marshal(name)
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var jsonNames = struct {
+	sync.RWMutex
+	names map[reflect.Type]map[string]bool
+}{names: make(map[reflect.Type]map[string]bool)}
+
+// jsonNamesOf returns lower case names from json tags
+// of struct type t fields, json matches keys case-insensitively.
+// Types with extra fields tag all their fields,
+// so embedded structs aren't expanded.
+func jsonNamesOf(t reflect.Type) map[string]bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	jsonNames.RLock()
+	names, ok := jsonNames.names[t]
+	jsonNames.RUnlock()
+	if ok {
+		return names
+	}
+	names = make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			names[strings.ToLower(name)] = true
+		}
+	}
+	jsonNames.Lock()
+	jsonNames.names[t] = names
+	jsonNames.Unlock()
+	return names
+}
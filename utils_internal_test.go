@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+	"gopkg.in/stretchr/testify.v1/require"
+)
+
+type extraPlain struct {
+	Name string `json:"name"`
+	Kind int    `json:"kind,omitempty"`
+	Skip string `json:"-"`
+}
+
+func TestUnmarshalExtra(t *testing.T) {
+	v := extraPlain{}
+	extra, err := unmarshalExtra([]byte(`{
+		"name": "known",
+		"KIND": 1,
+		"Skip": "y",
+		"new": true
+	}`), &v)
+	require.NoError(t, err)
+	assert.Equal(t, extraPlain{Name: "known", Kind: 1}, v)
+	assert.Equal(t, &ExtraFields{
+		"Skip": json.RawMessage(`"y"`),
+		"new":  json.RawMessage(`true`),
+	}, extra)
+
+	extra, err = unmarshalExtra([]byte(`{"name":"a"}`), &v)
+	require.NoError(t, err)
+	assert.Nil(t, extra)
+
+	// type errors don't stop decoding
+	v = extraPlain{}
+	extra, err = unmarshalExtra([]byte(`{"kind":"a","name":"b","new":1}`), &v)
+	assert.Error(t, err)
+	assert.Equal(t, "b", v.Name)
+	assert.Equal(t, &ExtraFields{"new": json.RawMessage(`1`)}, extra)
+
+	_, err = unmarshalExtra([]byte(`[1]`), &v)
+	assert.Error(t, err)
+}
+
+func TestMarshalExtra(t *testing.T) {
+	data, err := marshalExtra(extraPlain{Name: "known"}, &ExtraFields{
+		"new":  json.RawMessage(`true`),
+		"Name": json.RawMessage(`"hidden"`),
+		"a":    json.RawMessage(`1`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"known","a":1,"new":true}`, string(data))
+
+	data, err = marshalExtra(extraPlain{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":""}`, string(data))
+}